@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/chaitanyabsprip/note/cmd/note/config"
+	"github.com/chaitanyabsprip/note/cmd/note/views"
+	"github.com/chaitanyabsprip/note/internal/note"
+)
+
+// act performs the operation named by c.Action on an existing notes file,
+// reading any input it needs from stdin.
+func act(ctx context.Context, stdin io.Reader, w io.Writer, c *config.Config) error {
+	switch c.Action {
+	case config.ActionSetStatus:
+		if err := note.SetIssueStatus(c.Notespath, c.ID, c.Status); err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintf(w, "issue #%d is now %s\n", c.ID, strings.ToLower(string(c.Status)))
+		}
+	case config.ActionComment:
+		if err := note.AddIssueComment(c.Notespath, c.ID, c.Content, time.Now(), c.Width); err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintf(w, "commented on issue #%d\n", c.ID)
+		}
+	case config.ActionDone:
+		return completeTodo(w, c, c.Content)
+	case config.ActionUndo:
+		text, err := note.ReopenTodo(c.Notespath, c.Content)
+		if err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintln(w, "reopened:", text)
+		}
+	case config.ActionRollover:
+		count, err := note.RolloverTodos(c.Notespath, c.Copy, time.Now())
+		if err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintf(w, "carried %d todos over to today\n", count)
+		}
+	case config.ActionAddSubtask:
+		parent, err := note.AddSubtask(c.Notespath, c.Parent, c.Content, c.Width)
+		if err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintln(w, "added a subtask to:", parent)
+		}
+	case config.ActionRecur:
+		content := c.Content
+		if c.Priority != note.NoPriority {
+			// The priority is kept inline, as the rule only stores the todo.
+			content = fmt.Sprint(content, " !", c.Priority)
+		}
+		next, err := note.AddRecurringTodo(c.Notespath, c.Every, content, time.Now(), c.Width)
+		if err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintf(w, "every %s, next on %s\n", c.Every, next.Format("Mon, 02 Jan 2006"))
+		}
+	case config.ActionArchive:
+		count, err := note.ArchiveTodos(c.Notespath, c.OlderThan, time.Now())
+		if err != nil {
+			return err
+		}
+		if !c.Quiet {
+			archive := filepath.Base(note.ArchivePath(c.Notespath))
+			fmt.Fprintf(w, "archived %d todos to %s\n", count, archive)
+		}
+	case config.ActionRefresh:
+		refreshed, failed, err := note.RefreshBookmarks(ctx, c.Notespath, c.FetchTimeout, c.Width)
+		if err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintf(w, "refreshed %d bookmarks", refreshed)
+			if failed > 0 {
+				fmt.Fprintf(w, ", %d still could not be fetched", failed)
+			}
+			fmt.Fprintln(w)
+		}
+	case config.ActionCheck:
+		return note.CheckBookmarks(ctx, w, c.Notespath, note.LinkCheckOptions{
+			Mark:         c.Mark,
+			Timeout:      c.FetchTimeout,
+			FixRedirects: c.FixRedirects,
+		})
+	case config.ActionDedupe:
+		removed, err := note.DedupeBookmarks(c.Notespath, c.Width)
+		if err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintf(w, "merged %d duplicate bookmarks\n", removed)
+		}
+	case config.ActionImport:
+		return importBookmarks(w, c)
+	case config.ActionExport:
+		return note.ExportBookmarks(w, c.Notespath)
+	case config.ActionOpen:
+		return note.OpenNotes(note.Editor(c.Editor), c.Notespath, c.NoteType, time.Now())
+	case config.ActionBatch:
+		return addBookmarks(ctx, stdin, w, c)
+	case config.ActionList:
+		return list(w, c)
+	default:
+		return fmt.Errorf("unknown action %q", c.Action)
+	}
+	return nil
+}
+
+func list(w io.Writer, c *config.Config) error {
+	switch c.NoteType {
+	case note.Issue:
+		filter := note.IssueFilter{Status: c.Status, Labels: c.Tags, Since: c.Since}
+		return note.ListIssues(w, c.Notespath, filter, c.JSON, time.Now())
+	case note.Todo:
+		filter := note.TodoFilter{Due: c.Due, Overdue: c.Overdue}
+		return note.ListTodos(w, c.Notespath, filter, time.Now())
+	default:
+		return fmt.Errorf("cannot list %s notes", c.NoteType)
+	}
+}
+
+func importBookmarks(w io.Writer, c *config.Config) error {
+	file, err := os.Open(c.Content)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	imported, skipped, err := note.ImportBookmarks(c.Notespath, file, time.Now(), c.Width)
+	if err != nil {
+		return err
+	}
+	if !c.Quiet {
+		fmt.Fprintf(w, "imported %d bookmarks", imported)
+		if skipped > 0 {
+			fmt.Fprintf(w, ", skipped %d already bookmarked", skipped)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func addBookmarks(ctx context.Context, stdin io.Reader, w io.Writer, c *config.Config) error {
+	r := stdin
+	if c.From != "-" {
+		file, err := os.Open(c.From)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	n := note.Note{
+		NotesPath:    c.Notespath,
+		Tags:         c.Tags,
+		FetchTimeout: c.FetchTimeout,
+		Width:        c.Width,
+		Archive:      c.Archive,
+	}
+	added, skipped, err := n.AddBookmarks(ctx, r, time.Now())
+	if err != nil {
+		return err
+	}
+	if !c.Quiet {
+		fmt.Fprintf(w, "bookmarked %d pages", added)
+		if skipped > 0 {
+			fmt.Fprintf(w, ", skipped %d already bookmarked", skipped)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// completeTodo ticks off the todo matching query and, once every subtask of
+// its parent is done, offers to tick off the parent as well.
+func completeTodo(w io.Writer, c *config.Config, query string) error {
+	completed, err := note.CompleteTodo(c.Notespath, query, c.Stamp, time.Now())
+	if err != nil {
+		return err
+	}
+	if !c.Quiet {
+		fmt.Fprintln(w, "done:", completed.Text)
+	}
+	if completed.Parent == "" {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		fmt.Fprintf(w, "every subtask of %q is done\n", completed.Parent)
+		return nil
+	}
+	prompt := fmt.Sprintf("Every subtask of %q is done. Tick it off too?", completed.Parent)
+	if !views.Confirm(prompt) {
+		return nil
+	}
+	return completeTodo(w, c, strconv.Itoa(completed.ParentPosition))
+}
+
+// mergeDuplicateBookmark offers to merge the tags and description of the
+// bookmark in c into an existing bookmark of the same page, if there is one. It
+// reports whether there was one, in which case no new bookmark should be made.
+func mergeDuplicateBookmark(w io.Writer, c *config.Config) (bool, error) {
+	title, err := note.FindBookmark(c.Notespath, c.Content)
+	if err != nil || title == "" {
+		return false, err
+	}
+	fmt.Fprintf(w, "%s is already bookmarked as %q\n", c.Content, title)
+	if isTerminal(os.Stdin) && !views.Confirm("Merge the tags and description into it?") {
+		return true, nil
+	}
+	if err = note.MergeBookmark(c.Notespath, c.Content, c.Tags, c.Description, c.Width); err != nil {
+		return true, err
+	}
+	if !c.Quiet {
+		fmt.Fprintln(w, "merged into the existing bookmark")
+	}
+	return true, nil
+}
+
+// isTerminal reports whether f is connected to a terminal rather than a pipe
+// or a file.
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
@@ -11,6 +11,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -20,12 +21,18 @@ import (
 	"github.com/chaitanyabsprip/note/internal/project"
 )
 
+const defaultFetchTimeout = 5 * time.Second
+
 const (
 	version           = "v0.2.0"
 	projectEnv        = "PROJECT"
 	notesFileEnv      = "NOTESFILE"
 	quietEnv          = "QUIET"
 	editEnv           = "EDIT"
+	editorEnv         = "NOTE_EDITOR"
+	rolloverEnv       = "TODO_ROLLOVER"
+	fetchTimeoutEnv   = "BOOKMARK_TIMEOUT"
+	wrapWidthEnv      = "NOTES_WIDTH"
 	peekHeadingsCount = "NOTES_HEADINGS_COUNT"
 	peekHeadingsLevel = "NOTES_HEADINGS_LEVEL"
 )
@@ -33,6 +40,7 @@ const (
 // CommandTree struct  
 type CommandTree struct {
 	w                 io.Writer
+	stdin             io.Reader
 	getwd             func() (string, error)
 	projectRepository project.Repository
 	args              []string
@@ -42,14 +50,20 @@ type CommandTree struct {
 func (cp *CommandTree) SetupCLI() (*config.Config, error) {
 	c := new(config.Config)
 	c.EditFile = os.Getenv(editEnv) != ""
+	c.Editor = os.Getenv(editorEnv)
 	c.Project = os.Getenv(projectEnv)
 	c.Quiet = os.Getenv(quietEnv) != ""
 	c.Notespath = os.Getenv(notesFileEnv)
+	c.Rollover = os.Getenv(rolloverEnv) != ""
+	if cp.stdin != nil {
+		c.Piped = isPiped(cp.stdin)
+	}
 	rootCmd := createRootCmd(c)
 	rootCmd.AddCommand(
 		createBookmarkCmd(c),
 		createDumpCmd(c),
 		createIssueCmd(c),
+		createOpenCmd(c),
 		createPeekCmd(c),
 		createTodoCmd(c),
 	)
@@ -59,13 +73,58 @@ func (cp *CommandTree) SetupCLI() (*config.Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if c.FetchTimeout, err = fetchTimeout(); err != nil {
+		return nil, err
+	}
+	if c.Width, err = wrapWidth(c.NoteType); err != nil {
+		return nil, err
+	}
+	if err = cp.readContent(c); err != nil {
+		return nil, err
+	}
 	cp.determineFilepath(c)
 	return c, nil
 }
 
+// isPiped reports whether stdin has content piped or redirected into it, a
+// pipe or a file. Other readers, such as those given in tests, always do. An
+// inherited terminal, socket or device is not read so that note does not wait
+// on input that never comes.
+func isPiped(stdin io.Reader) bool {
+	f, ok := stdin.(*os.File)
+	if !ok {
+		return true
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
+
+// readContent reads the content of the note being made from stdin when it is
+// piped in and no content was given, or when "-" is given in its place.
+func (cp *CommandTree) readContent(c *config.Config) error {
+	makesNote := c.Action == "" || c.Action == config.ActionRecur
+	if !makesNote || c.Peek || cp.stdin == nil ||
+		c.Content != "-" && (c.Content != "" || !c.Piped) {
+		return nil
+	}
+	data, err := io.ReadAll(cp.stdin)
+	if err != nil {
+		return err
+	}
+	c.Content = strings.Trim(string(data), "\r\n")
+	c.Verbatim = true
+	return nil
+}
+
 func (cp *CommandTree) makeDumpCmdDefault(rootCmd *cobra.Command, c *config.Config) {
-	if !c.EditFile && (len(cp.args) == 0 || cp.args[0] == "help" || cp.args[0] == "completion" ||
-		cp.args[0][0] == '_') {
+	if len(cp.args) > 0 && (cp.args[0] == "help" || cp.args[0] == "completion" ||
+		strings.HasPrefix(cp.args[0], "_")) {
+		return
+	}
+	if len(cp.args) == 0 && !c.EditFile && !c.Piped {
 		return
 	}
 	var cmd *cobra.Command
@@ -83,6 +142,18 @@ func (cp *CommandTree) makeDumpCmdDefault(rootCmd *cobra.Command, c *config.Conf
 	cp.args = append([]string{createDumpCmd(c).Use}, cp.args...)
 }
 
+// addEditFlag adds the flag to write the note in an editor to cmd. It is also
+// set by the EDIT environment variable.
+func addEditFlag(cmd *cobra.Command, c *config.Config) {
+	cmd.Flags().BoolVarP(
+		&c.EditFile,
+		"edit",
+		"e",
+		c.EditFile,
+		"write the note in your editor, starting from a template filled in with the arguments",
+	)
+}
+
 func flagsContain(flags []string, contains ...string) bool {
 	for _, flag := range contains {
 		if slices.Contains(flags, flag) {
@@ -103,8 +174,8 @@ and issues efficiently from the command line.`,
 		Example: `# Create a new note
 	NOTESFILE=mynotes.md note
 
-# Edit an existing note
-	NOTESFILE=mynotes.md EDIT=1 note
+# Write a new note in your editor
+	NOTESFILE=mynotes.md note dump -e
 
 # Create a new todo
 	NOTESFILE=mynotes.md note todo
@@ -133,12 +204,35 @@ func createBookmarkCmd(c *config.Config) *cobra.Command {
 		note bookmark "" "" "OpenAI" https://www.openai.com
 
 		Add tags to a bookmark
-		note bookmark "" "ai, research" https://www.openai.com`,
+		note bookmark "" "ai, research" https://www.openai.com
+
+		Save an offline copy of the page along with the bookmark
+		note bookmark --archive https://go.dev/blog/loopvar-preview
+
+		Write the URL, tags and description of a bookmark in your editor
+		note bookmark -e https://go.dev/blog/loopvar-preview
+
+		Bookmark every URL in a file, one per line and optionally followed by tags
+		note bookmark --from urls.txt
+		xclip -o | note bookmark - reading`,
 		Aliases:               []string{"bm", "b"},
 		Args:                  cobra.MaximumNArgs(3),
 		DisableFlagsInUseLine: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c.NoteType = note.Bookmark
+			fromStdin := len(args) > 0 && args[0] == "-" || len(args) == 0 && c.Piped
+			if c.From != "" || fromStdin && !c.EditFile {
+				// Every argument is a tag once the URLs come from elsewhere.
+				if c.From == "" {
+					c.From = "-"
+				}
+				if len(args) > 0 && args[0] == "-" {
+					args = args[1:]
+				}
+				c.Action = config.ActionBatch
+				c.Tags = args
+				return nil
+			}
 			if len(args) == 0 && !c.EditFile {
 				var err error
 				*c, err = views.GetBookmarkConfiguration()
@@ -147,15 +241,141 @@ func createBookmarkCmd(c *config.Config) *cobra.Command {
 			if len(args) > 0 {
 				c.Content = args[0]
 			}
-			if len(args) >= 1 {
-				c.Tags = args[1:]
+			if len(args) > 1 {
+				c.Tags = args[1:2]
 			}
-			if len(args) >= 2 {
+			if len(args) > 2 {
 				c.Description = args[2]
 			}
 			return nil
 		},
 	}
+	cmd.AddCommand(
+		createBookmarkRefreshCmd(c),
+		createBookmarkCheckCmd(c),
+		createBookmarkDedupeCmd(c),
+		createBookmarkImportCmd(c),
+		createBookmarkExportCmd(c),
+	)
+	cmd.Flags().BoolVar(
+		&c.Archive,
+		"archive",
+		false,
+		"save an offline copy of the page next to the notes file",
+	)
+	cmd.Flags().StringVar(
+		&c.From,
+		"from",
+		"",
+		"bookmark the URLs listed in this file, or - for stdin, one per line",
+	)
+	addEditFlag(&cmd, c)
+	return &cmd
+}
+
+func createBookmarkRefreshCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "refresh",
+		Short: "Fetch the titles of bookmarks saved without them",
+		Long: `Fetch the page metadata of bookmarks that were saved without it, such as those
+made while offline, and rewrite them in place. Bookmarks titled with their own
+URL or flagged as unfetched are refreshed, several at a time.`,
+		Example: `# Backfill the titles of bookmarks made offline
+note bookmark refresh`,
+		Args: cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			c.NoteType = note.Bookmark
+			c.Action = config.ActionRefresh
+		},
+	}
+	return &cmd
+}
+
+func createBookmarkCheckCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "check",
+		Short: "Look for dead and redirected bookmarks",
+		Long: `Check every bookmarked link and report the ones that are dead, redirected or
+unreachable. Links are checked several at a time, with requests to the same host
+spaced out. Dead links can be marked in the file, and permanently redirected
+ones pointed to where they end up.`,
+		Example: `# Report dead and redirected links
+note bookmark check
+
+# Strike dead links through and fix permanent redirects
+note bookmark check --mark strike --fix-redirects`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			c.NoteType = note.Bookmark
+			c.Action = config.ActionCheck
+			if c.Mark != "" && c.Mark != note.MarkStrike && c.Mark != note.MarkTag {
+				return fmt.Errorf("invalid --mark %q, expected strike or tag", c.Mark)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&c.Mark, "mark", "", "mark dead links with strike or a dead tag")
+	cmd.Flags().BoolVar(
+		&c.FixRedirects,
+		"fix-redirects",
+		false,
+		"point permanently redirected links to where they end up",
+	)
+	return &cmd
+}
+
+func createBookmarkDedupeCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "dedupe",
+		Short: "Merge bookmarks of the same page",
+		Long: `Merge bookmarks that link to the same page into the earliest of them, combining
+their tags and descriptions. Links are compared ignoring case in the host,
+tracking parameters, trailing slashes and the order of query parameters.`,
+		Example: `# Clean up a bookmarks file
+note bookmark dedupe`,
+		Args: cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			c.NoteType = note.Bookmark
+			c.Action = config.ActionDedupe
+		},
+	}
+	return &cmd
+}
+
+func createBookmarkImportCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "import <file.html>",
+		Short: "Import bookmarks exported from a browser",
+		Long: `Import the bookmarks in a Netscape bookmark file, the bookmarks.html every
+browser exports. The folders a bookmark is in become its tags, and it is filed
+under the day it was added on. Pages that are already bookmarked are skipped.`,
+		Example: `# Import the bookmarks exported from a browser
+note bookmark import ~/Downloads/bookmarks.html`,
+		Args: cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			c.NoteType = note.Bookmark
+			c.Action = config.ActionImport
+			c.Content = args[0]
+		},
+	}
+	return &cmd
+}
+
+func createBookmarkExportCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "export",
+		Short: "Export bookmarks for a browser",
+		Long: `Print the bookmarks as a Netscape bookmark file that browsers can import.
+Bookmarks are put in a folder named after their first tag and keep all of their
+tags and the day they were filed under.`,
+		Example: `# Export the bookmarks to import them in a browser
+note bookmark export > bookmarks.html`,
+		Args: cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			c.NoteType = note.Bookmark
+			c.Action = config.ActionExport
+		},
+	}
 	return &cmd
 }
 
@@ -167,8 +387,8 @@ func createDumpCmd(c *config.Config) *cobra.Command {
 		Example: `Create a new note
 note dump "This is a quick note"
 
-Create a new note and edit it
-EDIT=1 note dump "This is a quick note"`,
+Write a new note in your editor, starting from "This is a quick note"
+note dump -e "This is a quick note"`,
 		Aliases: []string{"d"},
 		Args:    cobra.ArbitraryArgs,
 		Run: func(_ *cobra.Command, args []string) {
@@ -176,6 +396,7 @@ EDIT=1 note dump "This is a quick note"`,
 			c.Content = strings.Join(args, " ")
 		},
 	}
+	addEditFlag(&cmd, c)
 	return &cmd
 }
 
@@ -188,13 +409,19 @@ func createIssueCmd(c *config.Config) *cobra.Command {
 note issue "Bug in login feature" "The login feature fails when..."
 
 # Add tags to an issue
-note issue "Critical bug" "This is a critical issue..." "bug,urgent"`,
+note issue "Critical bug" "This is a critical issue..." "bug,urgent"
+
+# Write the issue's title, labels and description in your editor
+note issue -e "Critical bug"`,
 		Aliases:               []string{"i"},
 		Args:                  cobra.ArbitraryArgs,
 		DisableFlagsInUseLine: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c.NoteType = note.Issue
 			if !c.EditFile && len(args) == 0 {
+				if c.Piped {
+					return errors.New("give the issue a title, its description is read from stdin")
+				}
 				var err error
 				*c, err = views.GetIssueConfiguration()
 				return err
@@ -202,15 +429,231 @@ note issue "Critical bug" "This is a critical issue..." "bug,urgent"`,
 			if len(args) > 0 {
 				c.Title = args[0]
 			}
-			if len(args) >= 1 {
+			if len(args) > 1 {
 				c.Content = args[1]
 			}
-			if len(args) >= 2 {
+			if len(args) > 2 {
 				c.Tags = strings.Split(args[2], ",")
 			}
 			return nil
 		},
 	}
+	addEditFlag(&cmd, c)
+	cmd.AddCommand(
+		createIssueStatusCmd(c, "close", "Close an issue", note.Closed),
+		createIssueStatusCmd(c, "reopen", "Reopen a closed issue", note.Open),
+		createIssueStatusCmd(c, "start", "Mark an issue as in progress", note.InProgress),
+		createIssueCommentCmd(c),
+		createIssueListCmd(c),
+	)
+	return &cmd
+}
+
+func createIssueStatusCmd(
+	c *config.Config,
+	use, short string,
+	status note.Status,
+) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   use + " <id>",
+		Short: short,
+		Long: fmt.Sprintf(
+			"%s. The issue's status line is rewritten in place to %q.",
+			short,
+			status,
+		),
+		Example:               fmt.Sprintf("note issue %s 3", use),
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			id, err := parseIssueID(args[0])
+			if err != nil {
+				return err
+			}
+			c.NoteType = note.Issue
+			c.Action = config.ActionSetStatus
+			c.Status = status
+			c.ID = id
+			return nil
+		},
+	}
+	return &cmd
+}
+
+func createIssueCommentCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "comment <id> [text]",
+		Short: "Comment on an issue",
+		Long: `Add a timestamped comment under an issue's Comments section. A form is shown
+when no text is given.`,
+		Example: `# Comment on issue 3
+note issue comment 3 "Reproduced on staging"
+
+# Write the comment in a form
+note issue comment 3`,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			id, err := parseIssueID(args[0])
+			if err != nil {
+				return err
+			}
+			c.NoteType = note.Issue
+			c.Action = config.ActionComment
+			c.ID = id
+			c.Content = strings.Join(args[1:], " ")
+			if c.Content == "" {
+				c.Content, err = views.GetComment(id)
+			}
+			return err
+		},
+	}
+	return &cmd
+}
+
+func createIssueListCmd(c *config.Config) *cobra.Command {
+	var status, since string
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "List issues",
+		Long:  "List the issues of a project, optionally filtered by status, label and age.",
+		Example: `# List open bugs
+note issue list --status open --label bug
+
+# List issues created in the last week as JSON
+note issue list --since 7d --json`,
+		Aliases: []string{"ls"},
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			c.NoteType = note.Issue
+			c.Action = config.ActionList
+			var err error
+			if status != "" {
+				if c.Status, err = note.ParseStatus(status); err != nil {
+					return err
+				}
+			}
+			if since != "" {
+				if c.Since, err = parseAge(since); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(
+		&status,
+		"status",
+		"s",
+		"",
+		"only list issues with this status (open, closed, inprogress)",
+	)
+	cmd.Flags().StringSliceVarP(&c.Tags, "label", "l", nil, "only list issues with this label")
+	cmd.Flags().StringVar(&since, "since", "", "only list issues created within this long, e.g. 7d")
+	cmd.Flags().BoolVar(&c.JSON, "json", false, "print issues as JSON")
+	return &cmd
+}
+
+// parseAge parses durations such as "7d" or "2w" in addition to the units
+// understood by time.ParseDuration.
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// fetchTimeout reads how long fetching a bookmarked page may take from the
+// environment, where 0 means no limit.
+func fetchTimeout() (time.Duration, error) {
+	value := os.Getenv(fetchTimeoutEnv)
+	if value == "" {
+		return defaultFetchTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a duration such as 10s", fetchTimeoutEnv, value)
+	}
+	return timeout, nil
+}
+
+// wrapWidth reads the width notes of noteType are wrapped at from the
+// environment, such as TODO_WIDTH for todos, falling back to the width set for
+// every type of note. 0 means not to wrap, and nothing set the default width.
+func wrapWidth(noteType string) (int, error) {
+	envs := []string{wrapWidthEnv}
+	if noteType != "" {
+		envs = append([]string{strings.ToUpper(noteType) + "_WIDTH"}, envs...)
+	}
+	for _, env := range envs {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		width, err := strconv.Atoi(value)
+		if err != nil || width < 0 {
+			return 0, fmt.Errorf("invalid %s %q, expected a number of columns, or 0 not to wrap", env, value)
+		}
+		if width == 0 {
+			return note.NoWrap, nil
+		}
+		return width, nil
+	}
+	return 0, nil
+}
+
+func parseIssueID(arg string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid issue id %q", arg)
+	}
+	return id, nil
+}
+
+func createOpenCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "open [bookmark|issue|todo|dump]",
+		Short: "Open the notes in your editor",
+		Long: `Open the notes, todos, bookmarks, or issues in your editor, with the cursor on
+the newest entry for editors that can be told where to start: vim, nvim, emacs,
+nano, helix, kak and code among others. The editor is NOTE_EDITOR, VISUAL or
+EDITOR, whichever is set first, and may take arguments, as in "code -w".`,
+		Example: `# Open today's notes
+note open
+
+# Open the todos in VS Code
+NOTE_EDITOR="code -w" note open todo`,
+		Aliases:   []string{"o"},
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"bookmark", "bm", "b", "issue", "i", "todo", "t", "dump", "d"},
+		Run: func(_ *cobra.Command, args []string) {
+			c.Action = config.ActionOpen
+			c.NoteType = note.Dump
+			if len(args) == 0 {
+				return
+			}
+			switch args[0][0] {
+			case 'b':
+				c.NoteType = note.Bookmark
+			case 'i':
+				c.NoteType = note.Issue
+			case 't':
+				c.NoteType = note.Todo
+			}
+		},
+	}
 	return &cmd
 }
 
@@ -231,11 +674,14 @@ note peek -t
 note p -t
 
 # Preview notes
-note peek --dump`,
+note peek --dump
+
+# Read the offline copy of a bookmarked page
+note peek bookmark --snapshot https://go.dev/blog/loopvar-preview`,
 		Aliases:   []string{"p"},
 		Args:      cobra.OnlyValidArgs,
 		ValidArgs: []string{"bookmark", "bm", "b", "issue", "i", "todo", "t", "dump", "d"},
-		Run: func(_ *cobra.Command, args []string) {
+		RunE: func(_ *cobra.Command, args []string) error {
 			c.Peek = true
 			var err error
 			c.NumOfHeadings, err = strconv.Atoi(os.Getenv(peekHeadingsCount))
@@ -246,40 +692,256 @@ note peek --dump`,
 			if err != nil {
 				c.Level = 2
 			}
-			switch args[0][0] {
-			case 'b':
-				c.NoteType = note.Bookmark
-			case 'i':
-				c.NoteType = note.Issue
-			case 't':
-				c.NoteType = note.Todo
-			case 'd':
-				c.NoteType = note.Dump
-			default:
-				c.NoteType = note.Dump
+			c.NoteType = note.Dump
+			if len(args) > 0 {
+				switch args[0][0] {
+				case 'b':
+					c.NoteType = note.Bookmark
+				case 'i':
+					c.NoteType = note.Issue
+				case 't':
+					c.NoteType = note.Todo
+				}
+			}
+			if c.Snapshot != "" && c.NoteType != note.Bookmark {
+				return errors.New("only bookmarks have snapshots")
 			}
+			return nil
 		},
 	}
+	cmd.Flags().StringVar(
+		&c.Snapshot,
+		"snapshot",
+		"",
+		"show the offline copy of the bookmarked page at this URL",
+	)
 	return &cmd
 }
 
 func createTodoCmd(c *config.Config) *cobra.Command {
+	var due, priority string
 	cmd := cobra.Command{
 		Use:   "todo",
 		Short: "Create a new todo item",
-		Long:  "Create a new todo item to keep track of tasks and actions.",
+		Long: `Create a new todo item to keep track of tasks and actions. A due date can be
+given with --due or inline, as in "@tomorrow", "@fri" or "@2026-11-03", and a
+priority with --priority or inline, as in "!high", "!med" or "!low". With --every,
+the todo is added again under each day it comes around on, the first time a
+todo command is run on or after that day. A todo starting with the name of a subcommand, such
+as list or done, is given after --.`,
 		Example: `# Create a new todo
 note todo "Finish writing documentation"
 
-# Create a new todo and edit it
-EDIT=1 note todo "Finish writing documentation"`,
+# Write a new todo in your editor, with details on the lines after it
+note todo -e
+
+# Create a todo starting with the name of a subcommand
+note todo -- list the fixes
+
+# Create a todo that is due on Friday
+note todo --due fri "Send the report"
+note todo "Send the report @fri"
+
+# Create an urgent todo
+note todo --priority high "Fix the build"
+note todo "Fix the build !high"
+
+# Review PRs every Monday, and pay rent on the 1st of every month
+note todo --every monday "Review PRs"
+note todo --every 1st "Pay rent"`,
 		Aliases: []string{"td", "t"},
-		Run: func(_ *cobra.Command, args []string) {
+		RunE: func(_ *cobra.Command, args []string) error {
 			c.NoteType = note.Todo
 			c.Content = strings.Join(args, " ")
+			if c.Every != "" {
+				if due != "" {
+					return errors.New(
+						"--due cannot be used with --every, write the due date inline, " +
+							"as in @today, to have it worked out for every occurrence",
+					)
+				}
+				c.Action = config.ActionRecur
+			}
+			var err error
+			if priority != "" {
+				if c.Priority, err = note.ParsePriority(priority); err != nil {
+					return err
+				}
+			}
+			if due != "" {
+				c.Due, err = note.ParseDue(due, time.Now())
+			}
+			return err
 		},
 		Args: cobra.ArbitraryArgs,
 	}
+	cmd.Flags().StringVar(&due, "due", "", "when the todo is due, e.g. tomorrow, fri or 2026-11-03")
+	cmd.Flags().StringVarP(&priority, "priority", "p", "", "how urgent the todo is: high, med or low")
+	cmd.Flags().StringVar(
+		&c.Every,
+		"every",
+		"",
+		"repeat the todo every day, on a weekday such as monday or on a day of the month such as 1st",
+	)
+	addEditFlag(&cmd, c)
+	cmd.AddCommand(
+		createTodoDoneCmd(c),
+		createTodoUndoCmd(c),
+		createTodoRolloverCmd(c),
+		createTodoListCmd(c),
+		createTodoAddCmd(c),
+		createTodoArchiveCmd(c),
+	)
+	return &cmd
+}
+
+func createTodoAddCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "add [--parent <n|text>] <todo>",
+		Short: "Create a new todo item, or a subtask of an existing one",
+		Long: `Create a new todo item. With --parent, the todo is written as an indented
+subtask under the pending todo picked by its position or by text that matches
+it.`,
+		Example: `# Add a subtask to the first pending todo
+note todo add --parent 1 "Book a venue"`,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		Run: func(_ *cobra.Command, args []string) {
+			c.NoteType = note.Todo
+			c.Content = strings.Join(args, " ")
+			if c.Parent != "" {
+				c.Action = config.ActionAddSubtask
+			}
+		},
+	}
+	cmd.Flags().StringVar(&c.Parent, "parent", "", "the todo to add a subtask to")
+	return &cmd
+}
+
+func createTodoListCmd(c *config.Config) *cobra.Command {
+	var due string
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "List pending todos",
+		Long: `List pending todos, most urgent first and oldest first within the same
+priority. Each todo is shown with its position, which is what "note todo done"
+expects, and when it is due.`,
+		Example: `# List what is due today
+note todo list --due today
+
+# List what is overdue
+note todo list --overdue`,
+		Aliases: []string{"ls"},
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			c.NoteType = note.Todo
+			c.Action = config.ActionList
+			if due == "" {
+				return nil
+			}
+			var err error
+			c.Due, err = note.ParseDue(due, time.Now())
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&due, "due", "", "only list todos due on this day, e.g. today or fri")
+	cmd.Flags().BoolVar(&c.Overdue, "overdue", false, "only list todos past their due date")
+	return &cmd
+}
+
+func createTodoDoneCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "done <n|text>",
+		Short: "Tick off a todo",
+		Long: `Tick off a pending todo, picked either by its position among pending todos or by
+text that matches it.`,
+		Example: `# Tick off the second pending todo
+note todo done 2
+
+# Tick off the todo about documentation, noting when it was done
+note todo done --stamp docs`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			c.NoteType = note.Todo
+			c.Action = config.ActionDone
+			c.Content = strings.Join(args, " ")
+		},
+	}
+	cmd.Flags().BoolVarP(&c.Stamp, "stamp", "s", false, "append the completion time to the todo")
+	return &cmd
+}
+
+func createTodoRolloverCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "rollover",
+		Short: "Carry unfinished todos over to today",
+		Long: `Move every pending todo from earlier days into today's section, marking the day
+it came from. Set TODO_ROLLOVER=1 to do this automatically with the first todo of
+each day.`,
+		Example: `# Move yesterday's leftovers to today
+note todo rollover
+
+# Copy them instead, leaving the earlier days as they were
+note todo rollover --copy`,
+		Args: cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			c.NoteType = note.Todo
+			c.Action = config.ActionRollover
+		},
+	}
+	cmd.Flags().BoolVar(&c.Copy, "copy", false, "copy todos instead of moving them")
+	return &cmd
+}
+
+func createTodoArchiveCmd(c *config.Config) *cobra.Command {
+	var olderThan string
+	cmd := cobra.Command{
+		Use:   "archive",
+		Short: "Move completed todos into the archive",
+		Long: `Move completed todos, along with their subtasks once those are done too, into
+the archive file next to the todos file, e.g. notes.todo.archive.md, under the
+same date headings. Days that are left empty are removed from the todos file.`,
+		Example: `# Archive every completed todo
+note todo archive
+
+# Archive todos completed more than two weeks ago
+note todo archive --older-than 14d`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			c.NoteType = note.Todo
+			c.Action = config.ActionArchive
+			if olderThan == "" {
+				return nil
+			}
+			var err error
+			c.OlderThan, err = parseAge(olderThan)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(
+		&olderThan,
+		"older-than",
+		"",
+		"only archive todos completed at least this long ago, e.g. 14d",
+	)
+	return &cmd
+}
+
+func createTodoUndoCmd(c *config.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "undo <n|text>",
+		Short: "Untick a completed todo",
+		Long: `Untick a completed todo, picked either by its position among completed todos or
+by text that matches it.`,
+		Example: `# Untick the first completed todo
+note todo undo 1`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			c.NoteType = note.Todo
+			c.Action = config.ActionUndo
+			c.Content = strings.Join(args, " ")
+		},
+	}
 	return &cmd
 }
 
@@ -2,9 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chaitanyabsprip/note/cmd/note/config"
 	"github.com/chaitanyabsprip/note/internal/note"
@@ -17,112 +25,96 @@ var (
 	parseArgsTestCases = []struct {
 		desc   string
 		args   []string
+		env    map[string]string
 		config config.Config
 	}{
 		{
 			"with empty arguments, config should be empty (with defaults)",
 			[]string{},
-			config.Config{Notespath: getFilepath(""), Title: "Issue", NumOfHeadings: 3, Level: 2},
+			nil,
+			config.Config{Notespath: getFilepath(""), FetchTimeout: defaultFetchTimeout},
 		},
 		{
 			"with reserved help argument, config should be empty (with defaults)",
 			[]string{"help"},
-			config.Config{Notespath: getFilepath(""), Title: "Issue", NumOfHeadings: 3, Level: 2},
+			nil,
+			config.Config{Notespath: getFilepath(""), FetchTimeout: defaultFetchTimeout},
 		},
 		{
-			"with reserved help argument, config should be empty (with defaults)",
+			"with reserved completion argument, config should be empty (with defaults)",
 			[]string{"completion"},
-			config.Config{Notespath: getFilepath(""), Title: "Issue", NumOfHeadings: 3, Level: 2},
+			nil,
+			config.Config{Notespath: getFilepath(""), FetchTimeout: defaultFetchTimeout},
 		},
 		{
 			"with non-flag arguments, content should be set to concatenated arguments with defaults",
 			[]string{"hello"},
+			nil,
 			withDefaults(config.Config{Content: "hello"}),
 		},
 		{
 			"with '-e' flag, EditFile should be true, along with defaults",
 			[]string{"-e"},
+			nil,
 			withDefaults(
 				config.Config{NoteType: note.Dump, Notespath: getFilepath("dump"), EditFile: true},
 			),
 		},
 		{
-			"with '-f' flag, path should be set to the argument passed with the flag, tNotespath",
-			[]string{"-f", tNotespath},
-			withDefaults(config.Config{NoteType: note.Dump, Notespath: tNotespath}),
-		},
-		{
-			"with '-ef' flag, path should be set to the argument, tNotespath and EditFile as true",
-			[]string{"-ef", tNotespath},
-			withDefaults(config.Config{NoteType: note.Dump, Notespath: tNotespath, EditFile: true}),
+			"with EDIT set, EditFile should be true without arguments",
+			[]string{},
+			map[string]string{editEnv: "1"},
+			withDefaults(config.Config{EditFile: true}),
 		},
 		{
-			"with '-f' flag, path should be set to the argument passed with the flag, tAltNotespath",
-			[]string{"-f", tAltNotespath},
-			withDefaults(config.Config{NoteType: note.Dump, Notespath: tAltNotespath}),
+			"with NOTESFILE set, path should be set to it, tNotespath",
+			[]string{"hello"},
+			map[string]string{notesFileEnv: tNotespath},
+			withDefaults(config.Config{NoteType: note.Dump, Notespath: tNotespath, Content: "hello"}),
 		},
 		{
-			"with '-ef' flag, path should be set to the argument, tNotespath and EditFile as true",
-			[]string{"-ef", tAltNotespath},
+			"with NOTESFILE set and '-e' flag, path should be set to it, tAltNotespath and EditFile as true",
+			[]string{"-e"},
+			map[string]string{notesFileEnv: tAltNotespath},
 			withDefaults(
 				config.Config{NoteType: note.Dump, Notespath: tAltNotespath, EditFile: true},
 			),
 		},
 		{
-			"with '-q' flag, Quiet should be true, along with defaults",
-			[]string{"-q"},
-			withDefaults(config.Config{Quiet: true}),
-		},
-		{
-			"with '-qf' flag, path should be set to the argument, tNotespath and Quiet as true",
-			[]string{"-qf", tNotespath},
-			withDefaults(
-				config.Config{NoteType: note.Dump, Notespath: tNotespath, Quiet: true},
-			),
-		},
-		{
-			"with trailing non-flag arguments, Content must be concatenated arguments",
-			[]string{"-q", "This", "is", "content"},
-			withDefaults(config.Config{Quiet: true, Content: "This is content"}),
-		},
-		{
-			"with '-qf' flag, path should be set to the argument, tNotespath and Quiet as true",
-			[]string{"-qf", tAltNotespath},
-			withDefaults(
-				config.Config{NoteType: note.Dump, Notespath: tAltNotespath, Quiet: true},
-			),
+			"with QUIET set, Quiet should be true, along with defaults",
+			[]string{"hello"},
+			map[string]string{quietEnv: "1"},
+			withDefaults(config.Config{Quiet: true, Content: "hello"}),
 		},
 		{
-			"with '-qf' flag, path should be set to the argument, tNotespath and Quiet as true",
-			[]string{"-qf", tAltNotespath},
+			"with QUIET and NOTESFILE set, Content must be concatenated arguments",
+			[]string{"This", "is", "content"},
+			map[string]string{quietEnv: "1", notesFileEnv: tNotespath},
 			withDefaults(
-				config.Config{NoteType: note.Dump, Notespath: tAltNotespath, Quiet: true},
+				config.Config{
+					NoteType:  note.Dump,
+					Notespath: tNotespath,
+					Quiet:     true,
+					Content:   "This is content",
+				},
 			),
 		},
 		{
 			"with dump subcommand, Notespath should be <pwd>/notes.dump.md, IsDump should be true",
 			[]string{"dump"},
+			nil,
 			withDefaults(config.Config{}),
 		},
 		{
 			"with dump subcommand and args, content should be concatenated args with defaults",
 			[]string{"dump", "hello", "how"},
+			nil,
 			withDefaults(config.Config{Content: "hello how"}),
 		},
 		{
-			"with dump subcommand and '-q' flag, Quiet should be true with dump noteType",
-			[]string{"dump", "-q"},
-			withDefaults(
-				config.Config{
-					NoteType:  note.Dump,
-					Notespath: getFilepath("dump"),
-					Quiet:     true,
-				},
-			),
-		},
-		{
-			"with dump subcommand, trailing non-flag arguments, Content must be concatenated arguments",
-			[]string{"dump", "-q", "This", "is", "content"},
+			"with dump subcommand and QUIET set, Content must be concatenated arguments",
+			[]string{"dump", "This", "is", "content"},
+			map[string]string{quietEnv: "1"},
 			withDefaults(config.Config{
 				NoteType:  note.Dump,
 				Notespath: getFilepath("dump"),
@@ -133,6 +125,7 @@ var (
 		{
 			"with dump subcommand and '-e' flag, EditFile should be true with dump noteType",
 			[]string{"dump", "-e"},
+			nil,
 			withDefaults(
 				config.Config{
 					NoteType:  note.Dump,
@@ -142,70 +135,75 @@ var (
 			),
 		},
 		{
-			"with dump subcommand and '-f' flag, path should be set to the argument passed, tNotespath",
-			[]string{"dump", "-f", tNotespath},
+			"with dump subcommand and NOTESFILE set, path should be set to it, tNotespath",
+			[]string{"dump"},
+			map[string]string{notesFileEnv: tNotespath},
 			withDefaults(config.Config{NoteType: note.Dump, Notespath: tNotespath}),
 		},
 		{
 			"with peek subcommand, Notespath should be <pwd>/notes.dump.md, Peek should be true",
 			[]string{"peek"},
-			withDefaults(config.Config{Peek: true}),
+			nil,
+			withPeekDefaults(config.Config{}),
 		},
 		{
-			"with peek subcommand and '-b' flag, Notespath should be <pwd>/notes.bookmark.md, Peek should be true",
-			[]string{"peek", "-b"},
-			withDefaults(
-				config.Config{
-					NoteType:  note.Bookmark,
-					Peek:      true,
-					Notespath: getFilepath("bookmark"),
-				},
+			"with peek subcommand and bookmark type, Notespath should be <pwd>/notes.bookmark.md, Peek should be true",
+			[]string{"peek", "bookmark"},
+			nil,
+			withPeekDefaults(
+				config.Config{NoteType: note.Bookmark, Notespath: getFilepath("bookmark")},
 			),
 		},
 		{
-			"with peek subcommand and '-i' flag, Notespath should be <pwd>/notes.issue.md, Peek should be true",
-			[]string{"peek", "-i"},
-			withDefaults(
-				config.Config{NoteType: note.Issue, Peek: true, Notespath: getFilepath("issue")},
+			"with peek subcommand and issue type, Notespath should be <pwd>/notes.issue.md, Peek should be true",
+			[]string{"peek", "i"},
+			nil,
+			withPeekDefaults(
+				config.Config{NoteType: note.Issue, Notespath: getFilepath("issue")},
 			),
 		},
 		{
-			"with peek subcommand and '-t' flag, Notespath should be <pwd>/notes.todo.md, Peek should be true",
-			[]string{"peek", "-t"},
-			withDefaults(
-				config.Config{NoteType: note.Todo, Peek: true, Notespath: getFilepath("todo")},
+			"with peek subcommand and todo type, Notespath should be <pwd>/notes.todo.md, Peek should be true",
+			[]string{"p", "todo"},
+			nil,
+			withPeekDefaults(
+				config.Config{NoteType: note.Todo, Notespath: getFilepath("todo")},
 			),
 		},
 		{
-			"with peek subcommand and '-d' flag, Notespath should be <pwd>/notes.dump.md, Peek should be true",
-			[]string{"peek", "-d"},
-			withDefaults(
-				config.Config{Peek: true},
-			),
+			"with peek subcommand and dump type, Notespath should be <pwd>/notes.dump.md, Peek should be true",
+			[]string{"peek", "d"},
+			nil,
+			withPeekDefaults(config.Config{}),
 		},
 		{
-			"with peek subcommand and '-n' flag, NumOfHeadings should be set to arg with defaults",
-			[]string{"peek", "-n", "4"},
-			withDefaults(config.Config{Peek: true, NumOfHeadings: 4}),
+			"with peek subcommand and NOTES_HEADINGS_COUNT set, NumOfHeadings should be set to it",
+			[]string{"peek"},
+			map[string]string{peekHeadingsCount: "4"},
+			withPeekDefaults(config.Config{NumOfHeadings: 4}),
 		},
 		{
-			"with peek subcommand and '-l' flag, Level should be set to arg with defaults",
-			[]string{"peek", "-l", "1"},
-			withDefaults(config.Config{Peek: true, Level: 1}),
+			"with peek subcommand and NOTES_HEADINGS_LEVEL set, Level should be set to it",
+			[]string{"peek"},
+			map[string]string{peekHeadingsLevel: "1"},
+			withPeekDefaults(config.Config{Level: 1}),
 		},
 		{
-			"with peek subcommand and '-f' flag, path should be set to the argument passed with the flag, tNotespath",
-			[]string{"peek", "-f", tNotespath},
-			withDefaults(config.Config{Notespath: tNotespath, Peek: true}),
+			"with peek subcommand and NOTESFILE set, path should be set to it, tNotespath",
+			[]string{"peek"},
+			map[string]string{notesFileEnv: tNotespath},
+			withPeekDefaults(config.Config{Notespath: tNotespath}),
 		},
 		{
 			"with todo subcommand, Notespath should be <pwd>/notes.todo.md, IsTodo should be true",
 			[]string{"todo"},
+			nil,
 			withDefaults(config.Config{NoteType: note.Todo, Notespath: getFilepath("todo")}),
 		},
 		{
 			"with todo subcommand and args, content should be concatenated args with defaults",
 			[]string{"todo", "hello", "how"},
+			nil,
 			withDefaults(
 				config.Config{
 					NoteType:  note.Todo,
@@ -215,19 +213,9 @@ var (
 			),
 		},
 		{
-			"with todo subcommand and '-q' flag, Quiet should be true with todo noteType",
-			[]string{"todo", "-q"},
-			withDefaults(
-				config.Config{
-					NoteType:  note.Todo,
-					Notespath: getFilepath("todo"),
-					Quiet:     true,
-				},
-			),
-		},
-		{
-			"with todo subcommand, trailing non-flag arguments, Content must be concatenated arguments",
-			[]string{"todo", "-q", "This", "is", "content"},
+			"with todo subcommand and QUIET set, Content must be concatenated arguments",
+			[]string{"todo", "This", "is", "content"},
+			map[string]string{quietEnv: "1"},
 			withDefaults(config.Config{
 				NoteType:  note.Todo,
 				Notespath: getFilepath("todo"),
@@ -238,6 +226,7 @@ var (
 		{
 			"with todo subcommand and '-e' flag, EditFile should be true with todo noteType",
 			[]string{"todo", "-e"},
+			nil,
 			withDefaults(
 				config.Config{
 					NoteType:  note.Todo,
@@ -247,45 +236,40 @@ var (
 			),
 		},
 		{
-			"with todo subcommand and '-f' flag, path should be set to the argument passed, tNotespath",
-			[]string{"todo", "-f", tNotespath},
+			"with todo subcommand and NOTESFILE set, path should be set to it, tNotespath",
+			[]string{"todo"},
+			map[string]string{notesFileEnv: tNotespath},
 			withDefaults(config.Config{NoteType: note.Todo, Notespath: tNotespath}),
 		},
 		{
-			"with bookmark subcommand and '-q' flag, Quiet should be true with bookmark noteType",
-			[]string{"bookmark", "-q"},
-			withDefaults(
-				config.Config{
-					NoteType:  note.Bookmark,
-					Notespath: getFilepath("bookmark"),
-					Quiet:     true,
-				},
-			),
-		},
-		{
-			"with bookmark subcommand and args, content should be concatenated args with defaults",
-			[]string{"b", "hello", "how"},
+			"with bookmark subcommand and args, url, tag and description should be set",
+			[]string{"b", "hello", "how", "are you"},
+			nil,
 			withDefaults(
 				config.Config{
-					NoteType:  note.Bookmark,
-					Notespath: getFilepath("bookmark"),
-					Content:   "hello how",
+					NoteType:    note.Bookmark,
+					Notespath:   getFilepath("bookmark"),
+					Content:     "hello",
+					Tags:        []string{"how"},
+					Description: "are you",
 				},
 			),
 		},
 		{
-			"with bookmark subcommand, trailing non-flag arguments, Content must be concatenated arguments",
-			[]string{"bookmark", "-q", "This", "is", "content"},
+			"with bookmark subcommand and QUIET set, Quiet should be true with bookmark noteType",
+			[]string{"bookmark", "https://go.dev"},
+			map[string]string{quietEnv: "1"},
 			withDefaults(config.Config{
 				NoteType:  note.Bookmark,
 				Notespath: getFilepath("bookmark"),
 				Quiet:     true,
-				Content:   "This is content",
+				Content:   "https://go.dev",
 			}),
 		},
 		{
 			"with bookmark subcommand and '-e' flag, EditFile should be true with bookmark noteType",
 			[]string{"bookmark", "-e"},
+			nil,
 			withDefaults(
 				config.Config{
 					NoteType:  note.Bookmark,
@@ -295,67 +279,44 @@ var (
 			),
 		},
 		{
-			"with bookmark subcommand and '-f' flag, path should be set to the argument passed, tNotespath",
-			[]string{"bookmark", "-f", tNotespath},
-			withDefaults(config.Config{NoteType: note.Bookmark, Notespath: tNotespath}),
-		},
-		{
-			"with bookmark subcommand and '-d' flag, description should be set to the argument passed",
-			[]string{"bookmark", "-d", "New description"},
-			withDefaults(
-				config.Config{
-					NoteType:    note.Bookmark,
-					Notespath:   getFilepath("bookmark"),
-					Description: "New description",
-				},
-			),
-		},
-		{
-			"with bookmark subcommand and '-T' flag, description should be set to the argument passed",
-			[]string{"bookmark", "-T", "he,ll"},
+			"with bookmark subcommand and NOTESFILE set, path should be set to it, tNotespath",
+			[]string{"bookmark", "-e"},
+			map[string]string{notesFileEnv: tNotespath},
 			withDefaults(
-				config.Config{
-					NoteType:  note.Bookmark,
-					Notespath: getFilepath("bookmark"),
-					Tags:      []string{"he", "ll"},
-				},
+				config.Config{NoteType: note.Bookmark, Notespath: tNotespath, EditFile: true},
 			),
 		},
 		{
-			"with issue subcommand and '-q' flag, Quiet should be true with issue noteType",
-			[]string{"issue", "-q"},
+			"with issue subcommand and QUIET set, Quiet should be true with issue noteType",
+			[]string{"issue", "New title"},
+			map[string]string{quietEnv: "1"},
 			withDefaults(
 				config.Config{
 					NoteType:  note.Issue,
 					Notespath: getFilepath("issue"),
 					Quiet:     true,
+					Title:     "New title",
 				},
 			),
 		},
 		{
-			"with issue subcommand and args, content should be concatenated args with defaults",
-			[]string{"i", "hello", "how"},
+			"with issue subcommand and args, title, description and tags should be set",
+			[]string{"i", "hello", "how", "he,ll"},
+			nil,
 			withDefaults(
 				config.Config{
 					NoteType:  note.Issue,
 					Notespath: getFilepath("issue"),
-					Content:   "hello how",
+					Title:     "hello",
+					Content:   "how",
+					Tags:      []string{"he", "ll"},
 				},
 			),
 		},
-		{
-			"with issue subcommand, trailing non-flag arguments, Content must be concatenated arguments",
-			[]string{"issue", "-q", "This", "is", "content"},
-			withDefaults(config.Config{
-				NoteType:  note.Issue,
-				Notespath: getFilepath("issue"),
-				Quiet:     true,
-				Content:   "This is content",
-			}),
-		},
 		{
 			"with issue subcommand and '-e' flag, EditFile should be true with issue noteType",
 			[]string{"issue", "-e"},
+			nil,
 			withDefaults(
 				config.Config{
 					NoteType:  note.Issue,
@@ -365,31 +326,10 @@ var (
 			),
 		},
 		{
-			"with issue subcommand and '-f' flag, path should be set to the argument passed, tNotespath",
-			[]string{"issue", "-f", tNotespath},
-			withDefaults(config.Config{NoteType: note.Issue, Notespath: tNotespath}),
-		},
-		{
-			"with issue subcommand and '-t' flag, title should be set to the argument passed",
-			[]string{"issue", "-t", "New title"},
-			withDefaults(
-				config.Config{
-					NoteType:  note.Issue,
-					Notespath: getFilepath("issue"),
-					Title:     "New title",
-				},
-			),
-		},
-		{
-			"with issue subcommand and '-T' flag, description should be set to the argument passed",
-			[]string{"issue", "-T", "he,ll"},
-			withDefaults(
-				config.Config{
-					NoteType:  note.Issue,
-					Notespath: getFilepath("issue"),
-					Tags:      []string{"he", "ll"},
-				},
-			),
+			"with issue subcommand and NOTESFILE set, path should be set to it, tNotespath",
+			[]string{"issue", "-e"},
+			map[string]string{notesFileEnv: tNotespath},
+			withDefaults(config.Config{NoteType: note.Issue, Notespath: tNotespath, EditFile: true}),
 		},
 	}
 )
@@ -397,6 +337,11 @@ var (
 func TestFlagParser(t *testing.T) {
 	for _, tC := range parseArgsTestCases {
 		t.Run(tC.desc, func(t *testing.T) {
+			for _, env := range []string{
+				editEnv, quietEnv, notesFileEnv, peekHeadingsCount, peekHeadingsLevel,
+			} {
+				t.Setenv(env, tC.env[env])
+			}
 			cp := CommandTree{
 				w:                 new(bytes.Buffer),
 				getwd:             func() (string, error) { return tNotespath, nil },
@@ -416,6 +361,473 @@ func TestFlagParser(t *testing.T) {
 	}
 }
 
+func TestReadContent(t *testing.T) {
+	tests := []struct {
+		desc     string
+		args     []string
+		stdin    string
+		expected config.Config
+	}{
+		{
+			"piped into note, the output is dumped as is",
+			[]string{},
+			"--- FAIL: TestParse\nexit status 1\n",
+			config.Config{
+				NoteType: note.Dump,
+				Content:  "--- FAIL: TestParse\nexit status 1",
+				Verbatim: true,
+			},
+		},
+		{
+			"piped into todo, the output is the todo",
+			[]string{"todo"},
+			"call the bank\n",
+			config.Config{NoteType: note.Todo, Content: "call the bank", Verbatim: true},
+		},
+		{
+			"with - as the description, the issue is described by stdin",
+			[]string{"issue", "Build fails", "-"},
+			"make: *** [test] Error 1\n",
+			config.Config{
+				NoteType: note.Issue,
+				Title:    "Build fails",
+				Content:  "make: *** [test] Error 1",
+				Verbatim: true,
+			},
+		},
+		{
+			"with content given, stdin is left alone",
+			[]string{"dump", "hello"},
+			"ignored",
+			config.Config{NoteType: note.Dump, Content: "hello"},
+		},
+		{
+			"piped into bookmark, the urls are bookmarked",
+			[]string{"bookmark"},
+			"https://go.dev\n",
+			config.Config{NoteType: note.Bookmark, Action: config.ActionBatch, From: "-"},
+		},
+	}
+	for _, tC := range tests {
+		t.Run(tC.desc, func(t *testing.T) {
+			cp := CommandTree{
+				w:                 new(bytes.Buffer),
+				stdin:             strings.NewReader(tC.stdin),
+				getwd:             func() (string, error) { return tNotespath, nil },
+				args:              tC.args,
+				projectRepository: new(MockProjectRepository),
+			}
+			c, err := cp.SetupCLI()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.NoteType != tC.expected.NoteType || c.Content != tC.expected.Content ||
+				c.Title != tC.expected.Title || c.Action != tC.expected.Action ||
+				c.From != tC.expected.From || !c.Piped || c.Verbatim != tC.expected.Verbatim {
+				t.Errorf("SetupCLI() = %#+v, expected %#+v", *c, tC.expected)
+			}
+		})
+	}
+}
+
+func TestIsPiped(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	file, err := os.Create(filepath.Join(t.TempDir(), "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	tests := []struct {
+		desc     string
+		stdin    io.Reader
+		expected bool
+	}{
+		{"a pipe is read", r, true},
+		{"a redirected file is read", file, true},
+		{"a device is not read", devNull, false},
+		{"a reader is read", strings.NewReader(""), true},
+	}
+	for _, tC := range tests {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := isPiped(tC.stdin); got != tC.expected {
+				t.Errorf("isPiped() = %v, expected %v", got, tC.expected)
+			}
+		})
+	}
+}
+
+func TestMakeDumpCmdDefaultWhenPiped(t *testing.T) {
+	for _, args := range [][]string{
+		{"help"},
+		{"completion", "bash"},
+		{"__complete", "to"},
+		{"todo", "call the bank"},
+	} {
+		cp := CommandTree{args: args}
+		c := &config.Config{Piped: true}
+		rootCmd := createRootCmd(c)
+		rootCmd.AddCommand(createDumpCmd(c), createTodoCmd(c))
+		cp.makeDumpCmdDefault(rootCmd, c)
+		if !slices.Equal(cp.args, args) {
+			t.Errorf("makeDumpCmdDefault(%q) = %q, expected the args left alone", args, cp.args)
+		}
+	}
+	cp := CommandTree{}
+	c := &config.Config{Piped: true}
+	cp.makeDumpCmdDefault(createRootCmd(c), c)
+	if !slices.Equal(cp.args, []string{"dump"}) {
+		t.Errorf("makeDumpCmdDefault() = %q, expected the dump command", cp.args)
+	}
+}
+
+func TestPeekSnapshotExample(t *testing.T) {
+	var example string
+	for _, line := range strings.Split(createPeekCmd(new(config.Config)).Example, "\n") {
+		if strings.Contains(line, "--snapshot") {
+			example = line
+		}
+	}
+	args := strings.Fields(example)
+	if len(args) < 2 {
+		t.Fatalf("peek has no --snapshot example")
+	}
+	cp := CommandTree{
+		w:                 new(bytes.Buffer),
+		getwd:             func() (string, error) { return tNotespath, nil },
+		args:              args[1:],
+		projectRepository: new(MockProjectRepository),
+	}
+	c, err := cp.SetupCLI()
+	if err != nil {
+		t.Fatalf("%s: %v", example, err)
+	}
+	if !c.Peek || c.NoteType != note.Bookmark || c.Snapshot != args[len(args)-1] {
+		t.Errorf("%s: SetupCLI() = %#+v", example, *c)
+	}
+}
+
+func TestSubcommandParser(t *testing.T) {
+	today, err := note.ParseDue("today", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	action := func(noteType, action string, c config.Config) config.Config {
+		c.NoteType, c.Action = noteType, action
+		c.Notespath = getFilepath(noteType)
+		c.FetchTimeout = defaultFetchTimeout
+		return c
+	}
+	tests := []struct {
+		desc     string
+		args     []string
+		expected config.Config
+		wantErr  bool
+	}{
+		{
+			desc:     "issue list lists every issue",
+			args:     []string{"issue", "list"},
+			expected: action(note.Issue, config.ActionList, config.Config{}),
+		},
+		{
+			desc: "issue list filters by status, label and age",
+			args: []string{
+				"issue", "ls", "--status", "closed", "-l", "bug", "-l", "ui", "--since", "7d", "--json",
+			},
+			expected: action(note.Issue, config.ActionList, config.Config{
+				Status: note.Closed,
+				Tags:   []string{"bug", "ui"},
+				Since:  7 * 24 * time.Hour,
+				JSON:   true,
+			}),
+		},
+		{
+			desc:     "issue list accepts a status written with a dash",
+			args:     []string{"issue", "list", "-s", "in-progress"},
+			expected: action(note.Issue, config.ActionList, config.Config{Status: note.InProgress}),
+		},
+		{
+			desc:    "issue list rejects an unknown status",
+			args:    []string{"issue", "list", "--status", "done"},
+			wantErr: true,
+		},
+		{
+			desc:    "issue list rejects an invalid age",
+			args:    []string{"issue", "list", "--since", "soon"},
+			wantErr: true,
+		},
+		{
+			desc:     "issue close closes the issue",
+			args:     []string{"issue", "close", "3"},
+			expected: action(note.Issue, config.ActionSetStatus, config.Config{Status: note.Closed, ID: 3}),
+		},
+		{
+			desc:     "issue reopen takes an id written with #",
+			args:     []string{"issue", "reopen", "#4"},
+			expected: action(note.Issue, config.ActionSetStatus, config.Config{Status: note.Open, ID: 4}),
+		},
+		{
+			desc: "issue start marks the issue in progress",
+			args: []string{"issue", "start", "5"},
+			expected: action(note.Issue, config.ActionSetStatus, config.Config{
+				Status: note.InProgress,
+				ID:     5,
+			}),
+		},
+		{
+			desc:    "issue close rejects a non-numeric id",
+			args:    []string{"issue", "close", "three"},
+			wantErr: true,
+		},
+		{desc: "issue start requires an id", args: []string{"issue", "start"}, wantErr: true},
+		{
+			desc: "issue comment joins the rest of the arguments",
+			args: []string{"issue", "comment", "3", "reproduced", "on", "staging"},
+			expected: action(note.Issue, config.ActionComment, config.Config{
+				ID:      3,
+				Content: "reproduced on staging",
+			}),
+		},
+		{
+			desc:    "issue comment rejects a non-numeric id",
+			args:    []string{"issue", "comment", "x", "hi"},
+			wantErr: true,
+		},
+		{
+			desc:     "todo done picks the todo by position",
+			args:     []string{"todo", "done", "2"},
+			expected: action(note.Todo, config.ActionDone, config.Config{Content: "2"}),
+		},
+		{
+			desc: "todo done stamps the todo picked by text",
+			args: []string{"todo", "done", "--stamp", "write", "docs"},
+			expected: action(note.Todo, config.ActionDone, config.Config{
+				Content: "write docs",
+				Stamp:   true,
+			}),
+		},
+		{desc: "todo done requires a todo", args: []string{"todo", "done"}, wantErr: true},
+		{
+			desc:     "todo takes a todo starting with x",
+			args:     []string{"todo", "x", "marks", "the", "spot"},
+			expected: action(note.Todo, "", config.Config{Content: "x marks the spot"}),
+		},
+		{
+			desc:     "todo takes a todo starting with the name of a subcommand after --",
+			args:     []string{"todo", "--", "list", "the", "fixes"},
+			expected: action(note.Todo, "", config.Config{Content: "list the fixes"}),
+		},
+		{
+			desc:     "todo undo picks the todo",
+			args:     []string{"todo", "undo", "1"},
+			expected: action(note.Todo, config.ActionUndo, config.Config{Content: "1"}),
+		},
+		{
+			desc:     "todo rollover moves todos",
+			args:     []string{"todo", "rollover"},
+			expected: action(note.Todo, config.ActionRollover, config.Config{}),
+		},
+		{
+			desc:     "todo rollover --copy copies todos",
+			args:     []string{"todo", "rollover", "--copy"},
+			expected: action(note.Todo, config.ActionRollover, config.Config{Copy: true}),
+		},
+		{
+			desc:    "todo rollover takes no arguments",
+			args:    []string{"todo", "rollover", "now"},
+			wantErr: true,
+		},
+		{
+			desc:     "todo list lists pending todos",
+			args:     []string{"todo", "ls", "--overdue"},
+			expected: action(note.Todo, config.ActionList, config.Config{Overdue: true}),
+		},
+		{
+			desc:     "todo list --due filters by day",
+			args:     []string{"todo", "list", "--due", "today"},
+			expected: action(note.Todo, config.ActionList, config.Config{Due: today}),
+		},
+		{
+			desc:    "todo list rejects an invalid day",
+			args:    []string{"todo", "list", "--due", "someday"},
+			wantErr: true,
+		},
+		{
+			desc:     "todo add writes a todo",
+			args:     []string{"todo", "add", "call", "the", "bank"},
+			expected: action(note.Todo, "", config.Config{Content: "call the bank"}),
+		},
+		{
+			desc: "todo add --parent writes a subtask",
+			args: []string{"todo", "add", "--parent", "1", "book", "a", "venue"},
+			expected: action(note.Todo, config.ActionAddSubtask, config.Config{
+				Content: "book a venue",
+				Parent:  "1",
+			}),
+		},
+		{
+			desc:     "todo archive archives completed todos",
+			args:     []string{"todo", "archive"},
+			expected: action(note.Todo, config.ActionArchive, config.Config{}),
+		},
+		{
+			desc:     "todo archive --older-than takes weeks",
+			args:     []string{"todo", "archive", "--older-than", "2w"},
+			expected: action(note.Todo, config.ActionArchive, config.Config{OlderThan: 14 * 24 * time.Hour}),
+		},
+		{
+			desc:    "todo archive rejects an invalid age",
+			args:    []string{"todo", "archive", "--older-than", "old"},
+			wantErr: true,
+		},
+		{
+			desc:     "bookmark refresh refreshes bookmarks",
+			args:     []string{"bookmark", "refresh"},
+			expected: action(note.Bookmark, config.ActionRefresh, config.Config{}),
+		},
+		{
+			desc:     "bookmark check checks links",
+			args:     []string{"bm", "check"},
+			expected: action(note.Bookmark, config.ActionCheck, config.Config{}),
+		},
+		{
+			desc: "bookmark check marks dead links and fixes redirects",
+			args: []string{"bookmark", "check", "--mark", "strike", "--fix-redirects"},
+			expected: action(note.Bookmark, config.ActionCheck, config.Config{
+				Mark:         note.MarkStrike,
+				FixRedirects: true,
+			}),
+		},
+		{
+			desc:    "bookmark check rejects an unknown mark",
+			args:    []string{"bookmark", "check", "--mark", "bold"},
+			wantErr: true,
+		},
+		{
+			desc:     "bookmark dedupe merges duplicates",
+			args:     []string{"b", "dedupe"},
+			expected: action(note.Bookmark, config.ActionDedupe, config.Config{}),
+		},
+		{
+			desc:     "bookmark import reads the given file",
+			args:     []string{"bookmark", "import", "bookmarks.html"},
+			expected: action(note.Bookmark, config.ActionImport, config.Config{Content: "bookmarks.html"}),
+		},
+		{
+			desc:    "bookmark import requires a file",
+			args:    []string{"bookmark", "import"},
+			wantErr: true,
+		},
+		{
+			desc:     "bookmark export exports bookmarks",
+			args:     []string{"bookmark", "export"},
+			expected: action(note.Bookmark, config.ActionExport, config.Config{}),
+		},
+		{
+			desc:     "open opens the notes",
+			args:     []string{"open"},
+			expected: action(note.Dump, config.ActionOpen, config.Config{}),
+		},
+		{
+			desc:     "open todo opens the todos",
+			args:     []string{"o", "todo"},
+			expected: action(note.Todo, config.ActionOpen, config.Config{}),
+		},
+		{
+			desc:     "open takes the short name of a type",
+			args:     []string{"open", "bm"},
+			expected: action(note.Bookmark, config.ActionOpen, config.Config{}),
+		},
+		{desc: "open rejects an unknown type", args: []string{"open", "notes"}, wantErr: true},
+		{desc: "open takes a single type", args: []string{"open", "todo", "issue"}, wantErr: true},
+	}
+	for _, tC := range tests {
+		t.Run(tC.desc, func(t *testing.T) {
+			cp := CommandTree{
+				w:                 new(bytes.Buffer),
+				getwd:             func() (string, error) { return tNotespath, nil },
+				args:              tC.args,
+				projectRepository: new(MockProjectRepository),
+			}
+			c, err := cp.SetupCLI()
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("SetupCLI() error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				return
+			}
+			if !c.Equals(tC.expected) {
+				t.Errorf("SetupCLI() = %#+v, expected %#+v", *c, tC.expected)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age      string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{age: "7d", expected: 7 * 24 * time.Hour},
+		{age: "2w", expected: 14 * 24 * time.Hour},
+		{age: "0d"},
+		{age: "1h30m", expected: 90 * time.Minute},
+		{age: "", wantErr: true},
+		{age: "d", wantErr: true},
+		{age: "-1d", wantErr: true},
+		{age: "-5m", wantErr: true},
+		{age: "7x", wantErr: true},
+		{age: "a week", wantErr: true},
+	}
+	for _, tC := range tests {
+		got, err := parseAge(tC.age)
+		if (err != nil) != tC.wantErr {
+			t.Errorf("parseAge(%q) error = %v, wantErr %v", tC.age, err, tC.wantErr)
+			continue
+		}
+		if got != tC.expected {
+			t.Errorf("parseAge(%q) = %s, expected %s", tC.age, got, tC.expected)
+		}
+	}
+}
+
+func TestParseIssueID(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected int
+		wantErr  bool
+	}{
+		{arg: "3", expected: 3},
+		{arg: "#12", expected: 12},
+		{arg: "0", wantErr: true},
+		{arg: "-1", wantErr: true},
+		{arg: "abc", wantErr: true},
+		{arg: "3a", wantErr: true},
+		{arg: "#", wantErr: true},
+		{arg: "", wantErr: true},
+	}
+	for _, tC := range tests {
+		got, err := parseIssueID(tC.arg)
+		if (err != nil) != tC.wantErr {
+			t.Errorf("parseIssueID(%q) error = %v, wantErr %v", tC.arg, err, tC.wantErr)
+			continue
+		}
+		if got != tC.expected {
+			t.Errorf("parseIssueID(%q) = %d, expected %d", tC.arg, got, tC.expected)
+		}
+	}
+}
+
 type MockProjectRepository struct{}
 
 func (mpr *MockProjectRepository) GetProject(name string) *project.Project {
@@ -449,25 +861,176 @@ func (mpr *MockProjectRepository) UpdateProject(
 
 func withDefaults(config config.Config) config.Config {
 	updatedConfig := config
+	if updatedConfig.NoteType == "" {
+		updatedConfig.NoteType = note.Dump
+	}
+	if updatedConfig.Notespath == "" {
+		updatedConfig.Notespath = getFilepath(updatedConfig.NoteType)
+	}
+	updatedConfig.FetchTimeout = defaultFetchTimeout
+	return updatedConfig
+}
+
+func withPeekDefaults(config config.Config) config.Config {
+	updatedConfig := withDefaults(config)
+	updatedConfig.Peek = true
 	if updatedConfig.NumOfHeadings == 0 {
 		updatedConfig.NumOfHeadings = 3
 	}
 	if updatedConfig.Level == 0 {
 		updatedConfig.Level = 2
 	}
-	if updatedConfig.NoteType == "" {
-		updatedConfig.NoteType = note.Dump
-	}
-	if updatedConfig.Title == "" {
-		updatedConfig.Title = "Issue"
-	}
-	if updatedConfig.Notespath == "" {
-		updatedConfig.Notespath = getFilepath("dump")
-		updatedConfig.NoteType = note.Dump
-	}
 	return updatedConfig
 }
 
 func getFilepath(mode string) string {
 	return filepath.Join(tNotespath, fmt.Sprint("notes.", mode, ".md"))
 }
+
+func TestWrapWidth(t *testing.T) {
+	tests := []struct {
+		desc     string
+		noteType string
+		env      map[string]string
+		expected int
+		wantErr  bool
+	}{
+		{"with nothing set, the default width is used", note.Todo, nil, 0, false},
+		{
+			"with NOTES_WIDTH set, every type is wrapped at it",
+			note.Issue,
+			map[string]string{"NOTES_WIDTH": "100"},
+			100,
+			false,
+		},
+		{
+			"the width of the note type overrides NOTES_WIDTH",
+			note.Todo,
+			map[string]string{"NOTES_WIDTH": "100", "TODO_WIDTH": "60"},
+			60,
+			false,
+		},
+		{
+			"with a width of 0, notes are not wrapped",
+			note.Dump,
+			map[string]string{"DUMP_WIDTH": "0"},
+			note.NoWrap,
+			false,
+		},
+		{
+			"a width that is not a number is an error",
+			note.Bookmark,
+			map[string]string{"BOOKMARK_WIDTH": "wide"},
+			0,
+			true,
+		},
+	}
+	for _, tC := range tests {
+		t.Run(tC.desc, func(t *testing.T) {
+			for _, env := range []string{"NOTES_WIDTH", strings.ToUpper(tC.noteType) + "_WIDTH"} {
+				t.Setenv(env, tC.env[env])
+			}
+			width, err := wrapWidth(tC.noteType)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("wrapWidth() error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if width != tC.expected {
+				t.Errorf("wrapWidth() = %d, expected %d", width, tC.expected)
+			}
+		})
+	}
+}
+
+func TestAddsRecurringTodos(t *testing.T) {
+	tests := []struct {
+		desc     string
+		c        config.Config
+		expected bool
+	}{
+		{"making a todo adds them", config.Config{NoteType: note.Todo}, true},
+		{
+			"listing todos adds them",
+			config.Config{NoteType: note.Todo, Action: config.ActionList},
+			true,
+		},
+		{"making another note leaves them", config.Config{NoteType: note.Issue}, false},
+		{
+			"exporting bookmarks leaves them",
+			config.Config{NoteType: note.Bookmark, Action: config.ActionExport},
+			false,
+		},
+		{"peeking at todos leaves them", config.Config{NoteType: note.Todo, Peek: true}, false},
+		{
+			"opening todos leaves them",
+			config.Config{NoteType: note.Todo, Action: config.ActionOpen},
+			false,
+		},
+	}
+	for _, tC := range tests {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := addsRecurringTodos(&tC.c); got != tC.expected {
+				t.Errorf("addsRecurringTodos() = %v, expected %v", got, tC.expected)
+			}
+		})
+	}
+}
+
+func TestRecurringTodoFlags(t *testing.T) {
+	cp := CommandTree{
+		w:                 new(bytes.Buffer),
+		getwd:             func() (string, error) { return tNotespath, nil },
+		args:              []string{"todo", "--every", "monday", "--due", "fri", "Review PRs"},
+		projectRepository: new(MockProjectRepository),
+	}
+	if _, err := cp.SetupCLI(); err == nil || !strings.Contains(err.Error(), "--due") {
+		t.Errorf("SetupCLI() error = %v, expected --due to be rejected with --every", err)
+	}
+
+	notesPath := filepath.Join(t.TempDir(), "notes.todo.md")
+	c := config.Config{
+		NoteType:  note.Todo,
+		Action:    config.ActionRecur,
+		Every:     "daily",
+		Content:   "Stand up",
+		Priority:  note.HighPriority,
+		Notespath: notesPath,
+		Quiet:     true,
+	}
+	if err := act(context.Background(), nil, new(bytes.Buffer), &c); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- [ ] Stand up !high") {
+		t.Errorf("the recurring todo lost its priority:\n%s", data)
+	}
+}
+
+func TestRunBookmarksFromStdin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><head><title>Page %s</title></head></html>", r.URL.Path)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	notesPath := filepath.Join(dir, "notes.bookmark.md")
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv(notesFileEnv, notesPath)
+	t.Setenv(quietEnv, "1")
+	stdin := strings.NewReader(server.URL + "/a\n" + server.URL + "/b\n")
+	getwd := func() (string, error) { return dir, nil }
+	args := []string{"bookmark", "--from", "-", "reading"}
+	if _, err := run(context.Background(), args, getwd, stdin, new(bytes.Buffer)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range []string{"/a", "/b"} {
+		if link := fmt.Sprintf("[Page %s](%s%s)", page, server.URL, page); !strings.Contains(string(data), link) {
+			t.Errorf("run() did not bookmark %s from stdin:\n%s", link, data)
+		}
+	}
+}
@@ -3,39 +3,127 @@ package config
 
 import (
 	"slices"
+	"time"
 
 	"github.com/chaitanyabsprip/note/internal/note"
 )
 
+const (
+	// ActionSetStatus changes the status of an existing issue.
+	ActionSetStatus = "set-status"
+	// ActionComment appends a comment to an existing issue.
+	ActionComment = "comment"
+	// ActionList prints the entries of a notes file.
+	ActionList = "list"
+	// ActionDone ticks off a todo.
+	ActionDone = "done"
+	// ActionUndo unticks a completed todo.
+	ActionUndo = "undo"
+	// ActionRollover carries pending todos from earlier days over to today.
+	ActionRollover = "rollover"
+	// ActionAddSubtask writes a todo as a subtask of an existing one.
+	ActionAddSubtask = "add-subtask"
+	// ActionRecur saves a todo that is added again every time its rule comes
+	// around.
+	ActionRecur = "recur"
+	// ActionArchive moves completed todos into the archive file.
+	ActionArchive = "archive"
+	// ActionRefresh fetches the metadata of bookmarks written without it.
+	ActionRefresh = "refresh"
+	// ActionCheck looks for dead and redirected bookmark links.
+	ActionCheck = "check"
+	// ActionDedupe merges bookmarks of the same page.
+	ActionDedupe = "dedupe"
+	// ActionImport adds the links in a browser's bookmark file to the
+	// bookmarks.
+	ActionImport = "import"
+	// ActionExport writes the bookmarks as a browser's bookmark file.
+	ActionExport = "export"
+	// ActionBatch bookmarks every URL listed in a file or on stdin.
+	ActionBatch = "batch"
+	// ActionOpen opens a notes file in the editor at its newest entry.
+	ActionOpen = "open"
+)
+
 // Config struct  
 type Config struct {
+	Due           time.Time
+	Action        string
 	NoteType      string
 	Content       string
 	Description   string
+	Editor        string
+	Every         string
+	From          string
+	Mark          string
 	Notespath     string
+	Parent        string
 	Project       string
+	Snapshot      string
 	Title         string
 	Status        note.Status
 	Tags          []string
+	ID            int
+	Priority      note.Priority
+	Since         time.Duration
+	OlderThan     time.Duration
+	FetchTimeout  time.Duration
 	NumOfHeadings int
 	Level         int
+	Width         int
+	Archive       bool
 	EditFile      bool
-	Peek          bool
-	Quiet         bool
+	FixRedirects  bool
+	// Piped is set when stdin is not a terminal, so the content of the note
+	// can be read from it.
+	Piped bool
+	// Verbatim is set when the content was read from stdin, to be kept as is
+	// rather than formatted.
+	Verbatim bool
+	JSON     bool
+	Stamp    bool
+	Copy     bool
+	Rollover bool
+	Overdue  bool
+	Peek     bool
+	Quiet    bool
 }
 
 // Equals method  
 func (c Config) Equals(other Config) bool {
-	return c.Content == other.Content &&
+	return c.Action == other.Action &&
+		c.Content == other.Content &&
 		c.Peek == other.Peek &&
 		c.NoteType == other.NoteType &&
 		c.Description == other.Description &&
+		c.Editor == other.Editor &&
+		c.Every == other.Every &&
+		c.From == other.From &&
+		c.Mark == other.Mark &&
+		c.Archive == other.Archive &&
 		c.EditFile == other.EditFile &&
+		c.FixRedirects == other.FixRedirects &&
+		c.Piped == other.Piped &&
+		c.Verbatim == other.Verbatim &&
 		c.Level == other.Level &&
 		c.Notespath == other.Notespath &&
+		c.Parent == other.Parent &&
+		c.Snapshot == other.Snapshot &&
 		c.NumOfHeadings == other.NumOfHeadings &&
 		slices.Equal(c.Tags, other.Tags) &&
 		c.Title == other.Title &&
 		c.Status == other.Status &&
+		c.ID == other.ID &&
+		c.Since == other.Since &&
+		c.OlderThan == other.OlderThan &&
+		c.FetchTimeout == other.FetchTimeout &&
+		c.Width == other.Width &&
+		c.JSON == other.JSON &&
+		c.Stamp == other.Stamp &&
+		c.Copy == other.Copy &&
+		c.Rollover == other.Rollover &&
+		c.Overdue == other.Overdue &&
+		c.Priority == other.Priority &&
+		c.Due.Equal(other.Due) &&
 		c.Quiet == other.Quiet
 }
@@ -2,19 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"time"
 
+	"github.com/chaitanyabsprip/note/cmd/note/config"
 	"github.com/chaitanyabsprip/note/internal/note"
 	"github.com/chaitanyabsprip/note/internal/preview"
 	"github.com/chaitanyabsprip/note/internal/project"
 )
 
 func main() {
-	exitCode, err := run(context.Background(), os.Args[1:], os.Getwd, os.Stdout)
+	exitCode, err := run(context.Background(), os.Args[1:], os.Getwd, os.Stdin, os.Stdout)
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(exitCode)
@@ -25,9 +28,10 @@ func run(
 	ctx context.Context,
 	args []string,
 	getwd func() (string, error),
+	stdin io.Reader,
 	stdout io.Writer,
 ) (int, error) {
-	_, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
 	cachefile, err := getConfigFilepath()
 	if err != nil {
@@ -37,7 +41,13 @@ func run(
 	if err != nil {
 		return 1, err
 	}
-	cp := CommandTree{getwd: getwd, w: stdout, args: args, projectRepository: pr}
+	cp := CommandTree{
+		getwd:             getwd,
+		stdin:             stdin,
+		w:                 stdout,
+		args:              args,
+		projectRepository: pr,
+	}
 	c, err := cp.SetupCLI()
 	if err != nil {
 		return 1, err
@@ -47,6 +57,18 @@ func run(
 		!project.AlreadyExists(err) {
 		return 1, err
 	}
+	if addsRecurringTodos(c) {
+		if _, err = note.AddRecurringTodos(c.Notespath, time.Now(), c.Width); err != nil {
+			return 1, err
+		}
+	}
+
+	if c.Peek && c.Snapshot != "" {
+		if err = peekSnapshot(cp.w, c); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
 
 	if c.Peek {
 		p := preview.New(
@@ -63,6 +85,23 @@ func run(
 		return 0, nil
 	}
 
+	if c.Action != "" {
+		if err = act(ctx, cp.stdin, cp.w, c); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+
+	if c.NoteType == note.Bookmark && c.Content != "" && !c.EditFile {
+		var merged bool
+		if merged, err = mergeDuplicateBookmark(cp.w, c); err != nil {
+			return 1, err
+		}
+		if merged {
+			return 0, nil
+		}
+	}
+
 	var n note.Note
 	n, err = note.New(
 		c.Content,
@@ -77,13 +116,40 @@ func run(
 	if err != nil {
 		return 1, err
 	}
-	err = n.Note()
+	n.Rollover = c.Rollover
+	n.Due = c.Due
+	n.Priority = c.Priority
+	n.FetchTimeout = c.FetchTimeout
+	n.Width = c.Width
+	n.Editor = c.Editor
+	n.Archive = c.Archive
+	n.Verbatim = c.Verbatim
+	err = n.Note(ctx)
 	if err != nil {
 		return 1, err
 	}
 	return 0, nil
 }
 
+// addsRecurringTodos reports whether the command works on the todos, and so
+// adds the recurring todos that have come around first. Peeking at or opening
+// the todos leaves the file as it is.
+func addsRecurringTodos(c *config.Config) bool {
+	return c.NoteType == note.Todo && !c.Peek && c.Action != config.ActionOpen
+}
+
+// peekSnapshot renders the offline copy of the page bookmarked at c.Snapshot.
+func peekSnapshot(w io.Writer, c *config.Config) error {
+	data, err := os.ReadFile(note.SnapshotPath(c.Notespath, c.Snapshot))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("there is no snapshot of %s, bookmark it with --archive", c.Snapshot)
+	}
+	if err != nil {
+		return err
+	}
+	return preview.Render(w, string(data))
+}
+
 func getConfigFilepath() (string, error) {
 	configDir, err := os.UserCacheDir()
 	if err != nil {
@@ -86,3 +86,47 @@ func GetBookmarkConfiguration() (config.Config, error) {
 	c.Tags = strings.Split(tags, ",")
 	return *c, nil
 }
+
+// GetComment shows a form for writing a comment on the issue with the given id.
+func GetComment(id int) (string, error) {
+	comment := ""
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewText().
+				Title(fmt.Sprintf("Comment on #%d", id)).
+				Placeholder("What's new?").
+				Value(&comment).
+				WithHeight(4),
+		),
+	).WithTheme(ThemeRosepine()).Run()
+	if err != nil {
+		if err == huh.ErrUserAborted {
+			os.Exit(130)
+		}
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return comment, nil
+}
+
+// Confirm asks a yes or no question, defaulting to yes.
+func Confirm(title string) bool {
+	confirmed := true
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(title).
+				Affirmative("Yes").
+				Negative("No").
+				Value(&confirmed),
+		),
+	).WithTheme(ThemeRosepine()).Run()
+	if err != nil {
+		if err == huh.ErrUserAborted {
+			os.Exit(130)
+		}
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return confirmed
+}
@@ -0,0 +1,97 @@
+package note
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"time"
+)
+
+// ArchivePath returns the file that todos completed in the todos file at
+// notesPath are archived to, e.g. notes.todo.archive.md for notes.todo.md.
+func ArchivePath(notesPath string) string {
+	return strings.TrimSuffix(notesPath, ".md") + ".archive.md"
+}
+
+// ArchiveTodos moves the completed todos in the todos file at notesPath into
+// its archive file, under the same date headings they had. A todo is archived
+// along with its subtasks once all of them are done, and only if it was done,
+// or failing a done stamp its day began, at least olderThan ago. Days that are
+// left without any content are removed. It returns the number of todos
+// archived.
+//
+// The archive is written before the todos are removed, each file atomically,
+// so a crash in between leaves todos in both files rather than in neither.
+// Todos that are already under their day in the archive are only removed.
+func ArchiveTodos(notesPath string, olderThan time.Duration, now time.Time) (int, error) {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return 0, err
+	}
+	todos := parseTodos(data)
+	var archivable []int
+	for i, t := range todos {
+		if t.parent < 0 && t.section.heading != "" && isArchivable(todos, i, olderThan, now) {
+			archivable = append(archivable, i)
+		}
+	}
+	if len(archivable) == 0 {
+		return 0, nil
+	}
+	archivePath := ArchivePath(notesPath)
+	setupFile(archivePath, "Todo archive")
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	inArchive := parseTodos(archive)
+	var archived []span
+	var sections []dateSection
+	items := map[string]*bytes.Buffer{}
+	for _, i := range archivable {
+		t := todos[i]
+		end := subtreeEnd(todos, i)
+		archived = append(archived, span{t.start, end})
+		if containsTodo(inArchive, t.section.heading, t.text) {
+			continue
+		}
+		section, ok := items[t.section.heading]
+		if !ok {
+			section = new(bytes.Buffer)
+			items[t.section.heading] = section
+			sections = append(sections, t.section)
+		}
+		section.Write(data[t.start:end])
+		if data[end-1] != '\n' {
+			section.WriteString("\n")
+		}
+	}
+	for _, s := range sections {
+		if s.date.IsZero() {
+			archive = appendToSection(archive, s.heading, items[s.heading].Bytes())
+		} else {
+			archive = addToDaySection(archive, s.date, items[s.heading].Bytes())
+		}
+	}
+	if err = writeFileAtomic(archivePath, archive); err != nil {
+		return 0, err
+	}
+	data = removeEmptySections(removeSpans(data, archived))
+	return len(archived), writeFileAtomic(notesPath, data)
+}
+
+// isArchivable reports whether the i-th todo and all of its subtasks are done,
+// and were done at least olderThan before now.
+func isArchivable(todos []parsedTodo, i int, olderThan time.Duration, now time.Time) bool {
+	for j := i; j == i || (j < len(todos) && isDescendant(todos, j, i)); j++ {
+		t := todos[j]
+		doneAt := t.doneAt
+		if doneAt.IsZero() {
+			doneAt = t.section.date
+		}
+		if !t.done || (olderThan > 0 && (doneAt.IsZero() || now.Sub(doneAt) < olderThan)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package note
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const archiveFixture = `# Todo
+
+## Thu, 01 Oct 2026
+
+- [x] Book flights
+- [x] Plan the offsite
+  - [x] Pick a date
+  - [ ] Book a venue
+
+## Fri, 16 Oct 2026
+
+- [ ] Finish writing documentation
+- [x] Review PRs (done 2026-10-16 18:00)
+- [x] Write release notes
+`
+
+func TestArchiveTodos(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 9, 30, 0, 0, time.Local)
+	tests := []struct {
+		name      string
+		olderThan time.Duration
+		todos     string
+		archive   string
+		archived  int
+	}{
+		{
+			name: "All",
+			todos: `# Todo
+
+## Thu, 01 Oct 2026
+
+- [x] Plan the offsite
+  - [x] Pick a date
+  - [ ] Book a venue
+
+## Fri, 16 Oct 2026
+
+- [ ] Finish writing documentation
+`,
+			archive: `# Todo archive
+
+## Thu, 01 Oct 2026
+
+- [x] Book flights
+
+## Fri, 16 Oct 2026
+
+- [x] Review PRs (done 2026-10-16 18:00)
+- [x] Write release notes
+`,
+			archived: 3,
+		},
+		{
+			name:      "OlderThan",
+			olderThan: 24 * time.Hour,
+			todos: `# Todo
+
+## Thu, 01 Oct 2026
+
+- [x] Plan the offsite
+  - [x] Pick a date
+  - [ ] Book a venue
+
+## Fri, 16 Oct 2026
+
+- [ ] Finish writing documentation
+- [x] Review PRs (done 2026-10-16 18:00)
+`,
+			archive: `# Todo archive
+
+## Thu, 01 Oct 2026
+
+- [x] Book flights
+
+## Fri, 16 Oct 2026
+
+- [x] Write release notes
+`,
+			archived: 2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "notes.todo.md")
+			if err := os.WriteFile(path, []byte(archiveFixture), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			archived, err := ArchiveTodos(path, tc.olderThan, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if archived != tc.archived {
+				t.Errorf("ArchiveTodos() archived %d todos, expected %d", archived, tc.archived)
+			}
+			todos, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(todos) != tc.todos {
+				t.Errorf("ArchiveTodos() left:\n%s\nexpected:\n%s", todos, tc.todos)
+			}
+			archive, err := os.ReadFile(filepath.Join(filepath.Dir(path), "notes.todo.archive.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(archive) != tc.archive {
+				t.Errorf("ArchiveTodos() archived:\n%s\nexpected:\n%s", archive, tc.archive)
+			}
+		})
+	}
+}
+
+func TestArchiveTodosMergesDays(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 9, 30, 0, 0, time.Local)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.todo.md")
+	archive := "# Todo archive\n\n## Thu, 01 Oct 2026\n\n- [x] Pack\n\n" +
+		"## Sat, 17 Oct 2026\n\n- [x] Ship it\n"
+	if err := os.WriteFile(path, []byte(archiveFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ArchivePath(path), []byte(archive), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ArchiveTodos(path, 0, now); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(ArchivePath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Todo archive\n\n## Thu, 01 Oct 2026\n\n- [x] Pack\n- [x] Book flights\n\n" +
+		"## Fri, 16 Oct 2026\n\n- [x] Review PRs (done 2026-10-16 18:00)\n- [x] Write release notes\n\n" +
+		"## Sat, 17 Oct 2026\n\n- [x] Ship it\n"
+	if string(got) != expected {
+		t.Errorf("ArchiveTodos() archived:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestArchiveTodosAfterPartialWrite(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 9, 30, 0, 0, time.Local)
+	path := filepath.Join(t.TempDir(), "notes.todo.md")
+	if err := os.WriteFile(path, []byte(archiveFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ArchiveTodos(path, 0, now); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile(ArchivePath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The todos file is put back as if the run stopped after writing the archive.
+	if err = os.WriteFile(path, []byte(archiveFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ArchiveTodos(path, 0, now); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(ArchivePath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(expected) {
+		t.Errorf("ArchiveTodos() archived:\n%s\nexpected:\n%s", got, expected)
+	}
+	if got, err = os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(todos) {
+		t.Errorf("ArchiveTodos() left:\n%s\nexpected:\n%s", got, todos)
+	}
+}
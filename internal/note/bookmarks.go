@@ -0,0 +1,328 @@
+package note
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// fetchWorkers is how many pages are fetched at once.
+const fetchWorkers = 8
+
+// RefreshBookmarks fetches the metadata of the bookmarks in the file at
+// notesPath that were written without it, those flagged as unfetched or
+// titled with their own URL, and rewrites them in place wrapped at width. Each
+// fetch may take up to timeout, zero meaning no limit. It returns how many
+// bookmarks were refreshed and how many still could not be fetched.
+func RefreshBookmarks(
+	ctx context.Context,
+	notesPath string,
+	timeout time.Duration,
+	width int,
+) (int, int, error) {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	var stale []parsedBookmark
+	for _, b := range parseBookmarks(data) {
+		if b.unfetched || b.title == b.url {
+			b.timeout = timeout
+			b.width = width
+			stale = append(stale, b)
+		}
+	}
+	if len(stale) == 0 {
+		return 0, 0, nil
+	}
+	metas := make([]pageMeta, len(stale))
+	errs := make([]error, len(stale))
+	runConcurrently(len(stale), func(i int) {
+		metas[i], errs[i] = stale[i].fetch(ctx, stale[i].url)
+	})
+
+	current, err := os.ReadFile(notesPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !bytes.Equal(current, data) {
+		return 0, 0, errors.New("the bookmarks file changed while refreshing, try again")
+	}
+	var out bytes.Buffer
+	refreshed, failed, prev := 0, 0, 0
+	for i, b := range stale {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+		b.unfetched = false
+		out.Write(data[prev:b.start])
+		out.WriteString(b.entry(data, metas[i]))
+		prev = b.end
+		refreshed++
+	}
+	if refreshed == 0 {
+		return 0, failed, nil
+	}
+	out.Write(data[prev:])
+	return refreshed, failed, writeFileAtomic(notesPath, out.Bytes())
+}
+
+// AddBookmarks bookmarks the pages listed in r under now's date in the
+// file at n.NotesPath. Each line has a URL, optionally followed by tags, and
+// blank lines and lines starting with # are ignored. Pages are fetched several
+// at a time and written at once, in the order they are listed, along with
+// n.Tags. Pages that are already bookmarked are skipped. It returns how many
+// bookmarks were added and skipped.
+func (n Note) AddBookmarks(ctx context.Context, r io.Reader, now time.Time) (int, int, error) {
+	data, err := readBookmarksFile(n.NotesPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	seen := map[string]bool{}
+	for _, b := range parseBookmarks(data) {
+		seen[normalizeURL(b.url)] = true
+	}
+	var listed []bookmark
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		})
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		key := normalizeURL(fields[0])
+		if seen[key] {
+			skipped++
+			continue
+		}
+		seen[key] = true
+		b := bookmark{
+			url:     fields[0],
+			tags:    slices.Clone(n.Tags),
+			timeout: n.FetchTimeout,
+			width:   n.Width,
+		}
+		if n.Archive {
+			b.archiveTo = n.NotesPath
+		}
+		b.merge(bookmark{tags: fields[1:]})
+		listed = append(listed, b)
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	if len(listed) == 0 {
+		return 0, skipped, nil
+	}
+	entries := make([]string, len(listed))
+	runConcurrently(len(listed), func(i int) {
+		// Bookmarks only fail to render by not being fetched, which they are
+		// written without.
+		entries[i], _ = listed[i].toMarkdown(ctx, listed[i].url)
+	})
+	if err = ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	current, err := readBookmarksFile(n.NotesPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !bytes.Equal(current, data) {
+		return 0, 0, errors.New("the bookmarks file changed while bookmarking, try again")
+	}
+	data = addBookmarksToDay(data, startOfDay(now), entries)
+	if err = os.MkdirAll(filepath.Dir(n.NotesPath), 0o755); err != nil {
+		return 0, 0, err
+	}
+	return len(listed), skipped, writeFileAtomic(n.NotesPath, data)
+}
+
+// readBookmarksFile reads the bookmarks file at notesPath, which is empty
+// but for its title if it does not exist yet.
+func readBookmarksFile(notesPath string) ([]byte, error) {
+	data, err := os.ReadFile(notesPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return []byte(fmt.Sprintf("# %s\n", bookmark{}.label())), nil
+	}
+	return data, err
+}
+
+// addBookmarksToDay adds the bookmark entries to the end of the section for
+// day in data, keeping them a blank line apart.
+func addBookmarksToDay(data []byte, day time.Time, entries []string) []byte {
+	items := make([]string, len(entries))
+	for i, entry := range entries {
+		items[i] = strings.Trim(entry, "\n") + "\n"
+	}
+	joined := strings.Join(items, "\n")
+	if sectionHasContent(data, day.Format(headingDateFormat)) {
+		joined = "\n" + joined
+	}
+	return addToDaySection(data, day, []byte(joined))
+}
+
+// sectionHasContent reports whether data has a section with the given heading
+// that is not empty.
+func sectionHasContent(data []byte, heading string) bool {
+	for _, s := range parseSections(splitLines(data)) {
+		if s.heading == heading {
+			body := data[s.start+len("## "+heading) : s.end]
+			return len(bytes.TrimSpace(body)) > 0
+		}
+	}
+	return false
+}
+
+// FindBookmark returns the title of the bookmark in the file at notesPath that
+// links to the same page as rawURL, or "" if there is none.
+func FindBookmark(notesPath, rawURL string) (string, error) {
+	data, err := os.ReadFile(notesPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if b, ok := findBookmark(parseBookmarks(data), rawURL); ok {
+		return b.title, nil
+	}
+	return "", nil
+}
+
+// MergeBookmark adds tags and description to the bookmark in the file at
+// notesPath that links to the same page as rawURL, rewriting it wrapped at
+// width.
+func MergeBookmark(notesPath, rawURL string, tags []string, description string, width int) error {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return err
+	}
+	b, ok := findBookmark(parseBookmarks(data), rawURL)
+	if !ok {
+		return fmt.Errorf("could not find a bookmark of %s", rawURL)
+	}
+	b.merge(bookmark{tags: tags, description: description})
+	b.width = width
+	var out bytes.Buffer
+	out.Write(data[:b.start])
+	out.WriteString(b.entry(data, pageMeta{title: b.title}))
+	out.Write(data[b.end:])
+	return writeFileAtomic(notesPath, out.Bytes())
+}
+
+// DedupeBookmarks merges every bookmark in the file at notesPath into the
+// first one that links to the same page, removing it, and rewrites the others
+// wrapped at width. Days that are left without any content are removed. It
+// returns the number of bookmarks removed.
+func DedupeBookmarks(notesPath string, width int) (int, error) {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return 0, err
+	}
+	bookmarks := parseBookmarks(data)
+	first := map[string]int{}
+	removed := map[int]bool{}
+	for i, b := range bookmarks {
+		key := normalizeURL(b.url)
+		if j, ok := first[key]; ok {
+			bookmarks[j].merge(b.bookmark)
+			removed[i] = true
+			continue
+		}
+		first[key] = i
+	}
+	if len(removed) == 0 {
+		return 0, nil
+	}
+	var out bytes.Buffer
+	prev := 0
+	for i, b := range bookmarks {
+		out.Write(data[prev:b.start])
+		prev = b.end
+		if !removed[i] {
+			b.width = width
+			out.WriteString(b.entry(data, pageMeta{title: b.title}))
+			continue
+		}
+		// The blank lines that separated the entry from the next go with it.
+		for prev < len(data) && data[prev] == '\n' {
+			prev++
+		}
+	}
+	out.Write(data[prev:])
+	deduped := bytes.TrimRight(removeEmptySections(out.Bytes()), "\n")
+	if bytes.HasSuffix(data, []byte("\n")) {
+		deduped = append(deduped, '\n')
+	}
+	return len(removed), writeFileAtomic(notesPath, deduped)
+}
+
+func findBookmark(bookmarks []parsedBookmark, rawURL string) (parsedBookmark, bool) {
+	key := normalizeURL(rawURL)
+	for _, b := range bookmarks {
+		if normalizeURL(b.url) == key {
+			return b, true
+		}
+	}
+	return parsedBookmark{}, false
+}
+
+// merge adds the tags of other that b does not have yet to b, and its
+// description unless b's already says as much.
+func (b *bookmark) merge(other bookmark) {
+	for _, tag := range other.tags {
+		hasTag := func(t string) bool { return strings.EqualFold(t, tag) }
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.ContainsFunc(b.tags, hasTag) {
+			b.tags = append(b.tags, tag)
+		}
+	}
+	description := strings.TrimSpace(other.description)
+	if description != "" && !strings.Contains(b.description, description) {
+		b.description = strings.TrimSpace(b.description + "\n" + description)
+	}
+}
+
+// entry renders b to replace its entry in data, keeping the trailing newline
+// if the entry had one.
+func (b parsedBookmark) entry(data []byte, meta pageMeta) string {
+	entry := strings.TrimRight(b.format(b.url, meta), "\n")
+	if data[b.end-1] == '\n' {
+		entry += "\n"
+	}
+	return entry
+}
+
+// runConcurrently calls fn for every index below n, on up to fetchWorkers
+// goroutines at once, and waits for all of them to return.
+func runConcurrently(n int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(fetchWorkers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,350 @@
+package note
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshBookmarks(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		switch r.URL.Path {
+		case "/offline":
+			fmt.Fprint(w, "<title>Written offline</title>")
+		case "/flagged":
+			fmt.Fprint(w, `<title>Flagged</title><meta name="description" content="From the page itself, a description long enough to wrap">`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	placeholders := strings.NewReplacer("URL", server.URL, "TAGS", "tags: **#go**  ")
+	fixture := placeholders.Replace(`# Bookmarks
+
+## Fri, 16 Oct 2026
+
+[URL/offline](URL/offline)\
+TAGS
+Read later
+
+[Flagged](URL/flagged)\
+tags:
+<!-- unfetched -->
+
+[Fine](URL/fine)\
+tags:
+
+## Sat, 17 Oct 2026
+
+[URL/gone](URL/gone)\
+tags:
+<!-- unfetched -->
+`)
+	expected := placeholders.Replace(`# Bookmarks
+
+## Fri, 16 Oct 2026
+
+[Written offline](URL/offline)\
+TAGS
+Read later
+
+[Flagged](URL/flagged)\
+tags:
+From the page itself, a description long
+enough to wrap
+
+[Fine](URL/fine)\
+tags:
+
+## Sat, 17 Oct 2026
+
+[URL/gone](URL/gone)\
+tags:
+<!-- unfetched -->
+`)
+	path := writeFixture(t, fixture)
+	refreshed, failed, err := RefreshBookmarks(context.Background(), path, 0, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed != 2 || failed != 1 {
+		t.Errorf("RefreshBookmarks() = %d, %d, expected 2 refreshed and 1 failed", refreshed, failed)
+	}
+	if n := fetches.Load(); n != 3 {
+		t.Errorf("RefreshBookmarks() fetched %d pages, expected 3", n)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != expected {
+		t.Errorf("RefreshBookmarks() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestParseUnfetchedBookmark(t *testing.T) {
+	b := bookmark{description: "Read later", unfetched: true}
+	md := b.format("https://example.com", pageMeta{})
+	parsed := parseBookmarks([]byte(md))
+	if len(parsed) != 1 || !parsed[0].unfetched || parsed[0].description != "Read later" {
+		t.Errorf("parseBookmarks(%q) = %+v", md, parsed)
+	}
+}
+
+func TestFormatBookmarkWidth(t *testing.T) {
+	// Each word is 8 columns wide, though 12 bytes long.
+	title := "书签书签 书签书签 书签书签 书签书签 书签书签"
+	tests := []struct {
+		name     string
+		width    int
+		expected string
+	}{
+		{"FitsByDisplayWidth", 0, "[" + title + "](https://example.com/a)\\\n"},
+		{
+			"Narrow",
+			20,
+			"[书签书签 书签书签\n书签书签 书签书签\n书签书签](https://example.com/a)\\\n",
+		},
+		{"NoWrap", NoWrap, "[" + title + "](https://example.com/a)\\\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := bookmark{width: tc.width}
+			md := b.format("https://example.com/a", pageMeta{title: title})
+			if !strings.HasPrefix(md, tc.expected) {
+				t.Errorf("format() = %q, expected it to start with %q", md, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	for input, expected := range map[string]string{
+		"https://Example.COM/":                          "https://example.com",
+		"HTTPS://example.com/Docs/":                     "https://example.com/Docs",
+		"https://example.com/a?utm_source=x&b=2&a=1":    "https://example.com/a?a=1&b=2",
+		"https://example.com/a?fbclid=abc&UTM_Medium=y": "https://example.com/a",
+		"https://example.com/a#section":                 "https://example.com/a#section",
+		"not a url":                                     "not a url",
+	} {
+		if got := normalizeURL(input); got != expected {
+			t.Errorf("normalizeURL(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+const duplicatesFixture = `# Bookmarks
+
+## Thu, 15 Oct 2026
+
+[Example](https://example.com/post)\
+tags: **#go**  
+First read
+
+## Fri, 16 Oct 2026
+
+[Example](https://EXAMPLE.com/post/?utm_source=feed)\
+tags: **#Go** **#web**  
+Second read
+
+[Other](https://example.com/other)\
+tags:
+
+## Sat, 17 Oct 2026
+
+[Example](https://example.com/post?fbclid=1)\
+tags:
+First read
+`
+
+func TestMergeBookmark(t *testing.T) {
+	path := writeFixture(t, duplicatesFixture)
+	title, err := FindBookmark(path, "https://example.com/other/?utm_campaign=x")
+	if err != nil || title != "Other" {
+		t.Fatalf("FindBookmark() = %q, %v, expected Other", title, err)
+	}
+	if title, err = FindBookmark(path, "https://example.com/missing"); err != nil || title != "" {
+		t.Fatalf("FindBookmark() = %q, %v, expected no bookmark", title, err)
+	}
+	err = MergeBookmark(path, "https://example.com/other", []string{"later"}, "Read it later", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(
+		duplicatesFixture,
+		"[Other](https://example.com/other)\\\ntags:\n",
+		"[Other](https://example.com/other)\\\ntags: **#later**  \nRead it later\n",
+		1,
+	)
+	if string(got) != expected {
+		t.Errorf("MergeBookmark() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestRewriteBookmarksWidth(t *testing.T) {
+	fixture := strings.Replace(duplicatesFixture, "[Other]", "[The other page worth reading]", 1)
+	wrapped := "[The other page\nworth reading](https://example.com/other)"
+	tests := []struct {
+		name    string
+		rewrite func(path string) error
+	}{
+		{"Merge", func(path string) error {
+			return MergeBookmark(path, "https://example.com/other", []string{"later"}, "", 20)
+		}},
+		{"Dedupe", func(path string) error {
+			_, err := DedupeBookmarks(path, 20)
+			return err
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, fixture)
+			if err := tc.rewrite(path); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(got), wrapped) {
+				t.Errorf("wrote:\n%s\nexpected the title wrapped at 20 columns", got)
+			}
+		})
+	}
+}
+
+func TestDedupeBookmarks(t *testing.T) {
+	path := writeFixture(t, duplicatesFixture)
+	removed, err := DedupeBookmarks(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("DedupeBookmarks() removed %d bookmarks, expected 2", removed)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Bookmarks
+
+## Thu, 15 Oct 2026
+
+[Example](https://example.com/post)\
+tags: **#go** **#web**  
+First read
+Second read
+
+## Fri, 16 Oct 2026
+
+[Other](https://example.com/other)\
+tags:
+`
+	if string(got) != expected {
+		t.Errorf("DedupeBookmarks() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestAddBookmarks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<title>Page %s</title>", strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+	defer server.Close()
+	placeholders := strings.NewReplacer("URL", server.URL)
+	path := writeFixture(t, placeholders.Replace(`# Bookmarks
+
+## Sat, 17 Oct 2026
+
+[Page one](URL/one)\
+tags:
+`))
+	listed := placeholders.Replace(`URL/two go, web
+# a comment
+
+URL/one
+URL/three
+URL/gone later
+URL/two/
+`)
+	n := Note{NotesPath: path, Tags: []string{"batch"}}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	added, skipped, err := n.AddBookmarks(context.Background(), strings.NewReader(listed), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 3 || skipped != 2 {
+		t.Errorf("AddBookmarks() = %d, %d, expected 3 added and 2 skipped", added, skipped)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := placeholders.Replace(`# Bookmarks
+
+## Sat, 17 Oct 2026
+
+[Page one](URL/one)\
+tags:
+
+[Page two](URL/two)\
+tags: **#batch** **#go** **#web**  
+
+[Page three](URL/three)\
+tags: **#batch**  
+
+[URL/gone](URL/gone)\
+tags: **#batch** **#later**  
+<!-- unfetched -->
+`)
+	if string(got) != expected {
+		t.Errorf("AddBookmarks() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestBookmarkKeepsItsURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<title>Post</title><link rel="canonical" href="/canonical">`)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "notes.bookmark.md")
+	n := Note{NotesPath: path}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	pageURL := server.URL + "/post?utm_source=feed"
+	for i, expected := range []struct{ added, skipped int }{{1, 0}, {0, 1}} {
+		added, skipped, err := n.AddBookmarks(context.Background(), strings.NewReader(pageURL), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != expected.added || skipped != expected.skipped {
+			t.Errorf("AddBookmarks() #%d = %d, %d, expected %d added and %d skipped",
+				i+1, added, skipped, expected.added, expected.skipped)
+		}
+	}
+	if title, err := FindBookmark(path, pageURL); err != nil || title != "Post" {
+		t.Errorf("FindBookmark() = %q, %v, expected the bookmark titled Post", title, err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link := "[Post](" + server.URL + "/post)"; !strings.Contains(string(got), link) {
+		t.Errorf("AddBookmarks() wrote:\n%s\nexpected it to link to %s", got, link)
+	}
+}
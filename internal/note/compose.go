@@ -0,0 +1,132 @@
+package note
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// scissors marks where the text written in the editor ends, as in git's
+// commit messages. The help under it is left out of the note.
+const scissors = "------------------------ >8 ------------------------"
+
+// errEmptyNote is returned when a note composed in the editor is left empty.
+var errEmptyNote = errors.New("aborting, the note is empty")
+
+// composeHelp explains how what is written in the editor is read, for every
+// type of note.
+var composeHelp = map[string]string{
+	Dump: "Write the note above the line. Leaving it empty aborts.",
+	Todo: "Write the todo on the first line above the line. The lines after it are\n" +
+		"kept as they are under the todo. Leaving it empty aborts.",
+	Issue: "The first line above the line is the title of the issue, and a\n" +
+		"\"labels:\" line sets its labels, separated by commas. The rest is its\n" +
+		"description. Leaving the title empty aborts.",
+	Bookmark: "The first line above the line is the URL to bookmark, and a \"tags:\"\n" +
+		"line sets its tags, separated by commas. The rest is its description.\n" +
+		"Leaving the URL empty aborts.",
+}
+
+// compose opens a file filled in with n in editor, a command and its
+// arguments, for the note to be written in, and returns n as it was written
+// there.
+func (n Note) compose(editor []string) (Note, error) {
+	tmp, err := os.CreateTemp("", "note-*.md")
+	if err != nil {
+		return n, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(n.template())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, err
+	}
+	if err = runEditor(editor, tmp.Name()); err != nil {
+		return n, err
+	}
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return n, err
+	}
+	return n.readComposed(string(data))
+}
+
+// template returns the text the editor is opened with for n, the note as it
+// has been given so far followed by help on writing it.
+func (n Note) template() string {
+	var sb strings.Builder
+	switch n.Type {
+	case Issue:
+		fmt.Fprintf(&sb, "%s\n\nlabels: %s\n\n%s", n.Title, strings.Join(n.Tags, ", "), n.Content)
+	case Bookmark:
+		fmt.Fprintf(&sb, "%s\ntags: %s\n\n%s", n.Content, strings.Join(n.Tags, ", "), n.Description)
+	default:
+		sb.WriteString(n.Content)
+	}
+	fmt.Fprintf(&sb, "\n\n%s\n%s\n", scissors, composeHelp[n.Type])
+	return sb.String()
+}
+
+// readComposed returns n as written in the editor, as text laid out like its
+// template.
+func (n Note) readComposed(text string) (Note, error) {
+	text, _, _ = strings.Cut(text, scissors)
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return n, errEmptyNote
+	}
+	switch n.Type {
+	case Todo:
+		n.Content = text
+		// The lines after the todo are its details, kept as they are.
+		n.Verbatim = strings.Contains(text, "\n")
+	case Issue, Bookmark:
+		first, rest, _ := strings.Cut(text, "\n")
+		field := "tags:"
+		if n.Type == Issue {
+			field = "labels:"
+		}
+		if strings.HasPrefix(first, field) {
+			return n, errEmptyNote
+		}
+		if n.Type == Issue {
+			n.Title = strings.TrimSpace(strings.TrimLeft(first, "# "))
+		} else {
+			n.Content = strings.TrimSpace(first)
+		}
+		var body []string
+		for _, line := range strings.Split(rest, "\n") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(line), field); ok && len(body) == 0 {
+				n.Tags = splitTags(value)
+				continue
+			}
+			if len(body) > 0 || strings.TrimSpace(line) != "" {
+				body = append(body, line)
+			}
+		}
+		description := strings.TrimSpace(strings.Join(body, "\n"))
+		if n.Type == Issue {
+			n.Content = description
+		} else {
+			n.Description = description
+		}
+	default:
+		n.Content = text
+	}
+	return n, nil
+}
+
+// splitTags splits a list of tags separated by commas, dropping the # they
+// may be written with.
+func splitTags(value string) []string {
+	var tags []string
+	for _, field := range strings.Split(value, ",") {
+		if tag := strings.Trim(field, " \t#*"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,137 @@
+package note
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadComposed(t *testing.T) {
+	help := "\n" + scissors + "\nignored: help text\n"
+	tests := []struct {
+		name     string
+		note     Note
+		text     string
+		expected Note
+		wantErr  bool
+	}{
+		{
+			name:     "Dump",
+			note:     Note{Type: Dump},
+			text:     "a note\n\nwith two paragraphs\n" + help,
+			expected: Note{Type: Dump, Content: "a note\n\nwith two paragraphs"},
+		},
+		{
+			name:     "Todo",
+			note:     Note{Type: Todo},
+			text:     "call the bank\n",
+			expected: Note{Type: Todo, Content: "call the bank"},
+		},
+		{
+			name:     "TodoWithDetails",
+			note:     Note{Type: Todo},
+			text:     "call the bank\naccount 1234\n" + help,
+			expected: Note{Type: Todo, Content: "call the bank\naccount 1234", Verbatim: true},
+		},
+		{
+			name: "Issue",
+			note: Note{Type: Issue, Title: "Old title", Tags: []string{"old"}},
+			text: "Login fails\n\nlabels: bug, good first issue\n\nSteps:\n\n1. Log in\n" + help,
+			expected: Note{
+				Type:    Issue,
+				Title:   "Login fails",
+				Tags:    []string{"bug", "good first issue"},
+				Content: "Steps:\n\n1. Log in",
+			},
+		},
+		{
+			name: "Bookmark",
+			note: Note{Type: Bookmark},
+			text: "https://go.dev\ntags: go, **#lang**\n\nThe Go website\n" + help,
+			expected: Note{
+				Type:        Bookmark,
+				Content:     "https://go.dev",
+				Tags:        []string{"go", "lang"},
+				Description: "The Go website",
+			},
+		},
+		{
+			name:    "Empty",
+			note:    Note{Type: Dump, Content: "given"},
+			text:    "\n\n" + help,
+			wantErr: true,
+		},
+		{
+			name:    "IssueWithoutTitle",
+			note:    Note{Type: Issue},
+			text:    "\n\nlabels: bug\n\ndescription\n" + help,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.note.readComposed(tc.text)
+			if tc.wantErr {
+				if !errors.Is(err, errEmptyNote) {
+					t.Errorf("readComposed() error = %v, expected %v", err, errEmptyNote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Content != tc.expected.Content || got.Title != tc.expected.Title ||
+				got.Description != tc.expected.Description || got.Verbatim != tc.expected.Verbatim ||
+				!slices.Equal(got.Tags, tc.expected.Tags) {
+				t.Errorf("readComposed() = %+v, expected %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestTemplateRoundTrip(t *testing.T) {
+	n := Note{
+		Type:    Issue,
+		Title:   "Login fails",
+		Tags:    []string{"bug", "auth"},
+		Content: "It fails.",
+	}
+	got, err := n.readComposed(n.template())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != n.Title || got.Content != n.Content || !slices.Equal(got.Tags, n.Tags) {
+		t.Errorf("readComposed(template()) = %+v, expected %+v", got, n)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editor is a shell script")
+	}
+	editor := filepath.Join(t.TempDir(), "editor")
+	script := "#!/bin/sh\nprintf 'written in the editor\\n' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	notesPath := filepath.Join(t.TempDir(), "notes.todo.md")
+	n := Note{Type: Todo, NotesPath: notesPath, EditFile: true, HidePreview: true}
+	composed, err := n.compose([]string{editor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if composed.Content != "written in the editor" {
+		t.Errorf("compose() content = %q", composed.Content)
+	}
+	if _, err = os.Stat(notesPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("compose() touched the notes file: %v", err)
+	}
+	if err = runEditor([]string{filepath.Join(t.TempDir(), "missing")}); err == nil ||
+		!strings.Contains(err.Error(), "could not run") {
+		t.Errorf("runEditor() error = %v", err)
+	}
+}
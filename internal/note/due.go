@@ -0,0 +1,66 @@
+package note
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dueToken matches an "@when" due date written inline in a todo.
+var dueToken = regexp.MustCompile(`(^|\s)@(\S+)`)
+
+// ParseDue resolves a due date such as "today", "tomorrow", "fri", "+3d" or
+// "2026-11-03" relative to now. Weekdays refer to the next such day, counting
+// today.
+func ParseDue(s string, now time.Time) (time.Time, error) {
+	today := startOfDay(now)
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "today", "tod":
+		return today, nil
+	case "tomorrow", "tmr", "tom":
+		return today.AddDate(0, 0, 1), nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return today.AddDate(0, 0, (int(day)-int(today.Weekday())+7)%7), nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(s, "+"); ok && len(rest) > 1 {
+		n, err := strconv.Atoi(rest[:len(rest)-1])
+		if err == nil {
+			switch rest[len(rest)-1] {
+			case 'd':
+				return today.AddDate(0, 0, n), nil
+			case 'w':
+				return today.AddDate(0, 0, 7*n), nil
+			}
+		}
+	}
+	if due, err := time.ParseInLocation(time.DateOnly, s, now.Location()); err == nil {
+		return due, nil
+	}
+	return time.Time{}, fmt.Errorf("could not understand due date %q", s)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// resolveDueDates rewrites the inline "@when" due dates in content to the
+// "@2006-01-02" form they are stored in. Tokens that are not dates, such as
+// mentions, are left alone.
+func resolveDueDates(content string, now time.Time) string {
+	return dueToken.ReplaceAllStringFunc(content, func(token string) string {
+		m := dueToken.FindStringSubmatch(token)
+		due, err := ParseDue(m[2], now)
+		if err != nil {
+			return token
+		}
+		return m[1] + "@" + due.Format(time.DateOnly)
+	})
+}
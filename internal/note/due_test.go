@@ -0,0 +1,56 @@
+package note
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	// A Saturday.
+	now := time.Date(2026, time.October, 17, 15, 4, 0, 0, time.UTC)
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "today", expected: "2026-10-17"},
+		{input: "Tomorrow", expected: "2026-10-18"},
+		{input: "sat", expected: "2026-10-17"},
+		{input: "fri", expected: "2026-10-23"},
+		{input: "monday", expected: "2026-10-19"},
+		{input: "+3d", expected: "2026-10-20"},
+		{input: "+2w", expected: "2026-10-31"},
+		{input: "2026-11-03", expected: "2026-11-03"},
+		{input: "someday", wantErr: true},
+		{input: "+d", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseDue(tc.input, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseDue() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && got.Format(time.DateOnly) != tc.expected {
+				t.Errorf("ParseDue() = %v, expected %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestResolveDueDates(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 15, 4, 0, 0, time.UTC)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "send the report @fri", expected: "send the report @2026-10-23"},
+		{input: "@tomorrow call bob", expected: "@2026-10-18 call bob"},
+		{input: "ping @alice about it", expected: "ping @alice about it"},
+		{input: "mail me@example.com", expected: "mail me@example.com"},
+	}
+	for _, tc := range tests {
+		if got := resolveDueDates(tc.input, now); got != tc.expected {
+			t.Errorf("resolveDueDates(%q) = %q, expected %q", tc.input, got, tc.expected)
+		}
+	}
+}
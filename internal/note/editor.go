@@ -0,0 +1,99 @@
+package note
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fallbackEditors are tried in order when no editor is set, the first one
+// found being used.
+var fallbackEditors = []string{"nvim", "vim", "vi", "nano"}
+
+// Editor returns the command to edit files with and its arguments: the
+// configured one, or else that of $VISUAL or $EDITOR, or else the first of
+// fallbackEditors that is installed. Commands are split on spaces, so that
+// they may take arguments, as in "code -w".
+func Editor(configured string) []string {
+	for _, editor := range []string{configured, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if fields := strings.Fields(editor); len(fields) > 0 {
+			return fields
+		}
+	}
+	for _, editor := range fallbackEditors {
+		if _, err := exec.LookPath(editor); err == nil {
+			return []string{editor}
+		}
+	}
+	return fallbackEditors[:1]
+}
+
+// OpenNotes opens the notes file at notesPath in editor, with the cursor on
+// its newest entry for the editors that can be told which line to start on.
+func OpenNotes(editor []string, notesPath, noteType string, now time.Time) error {
+	data, err := os.ReadFile(notesPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("there are no notes at %s yet", notesPath)
+	}
+	if err != nil {
+		return err
+	}
+	return runEditor(editor, lineArgs(editor[0], notesPath, newestEntryLine(data, noteType, now))...)
+}
+
+// newestEntryLine returns the line number, counting from 1, of the newest
+// entry of noteType in data: the heading of its last issue in an issues file,
+// and the heading of now's day, or else of the last day, in the others.
+func newestEntryLine(data []byte, noteType string, now time.Time) int {
+	offset := 0
+	if noteType == Issue {
+		lines := strings.SplitAfter(string(data), "\n")
+		if headings := issueHeadings(lines); len(headings) > 0 {
+			return headings[len(headings)-1] + 1
+		}
+		return 1
+	}
+	today := now.Format(headingDateFormat)
+	for _, s := range parseSections(splitLines(data)) {
+		offset = s.start
+		if s.heading == today {
+			break
+		}
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// lineArgs returns the arguments that open file in editor with the cursor on
+// line, for the editors that are known to take one, and just file for others.
+func lineArgs(editor, file string, line int) []string {
+	n := strconv.Itoa(line)
+	switch strings.TrimSuffix(filepath.Base(editor), ".exe") {
+	case "vi", "vim", "nvim", "gvim", "nano", "emacs", "emacsclient", "kak", "micro":
+		return []string{"+" + n, file}
+	case "hx", "helix", "subl":
+		return []string{file + ":" + n}
+	case "code", "code-insiders", "codium":
+		return []string{"--goto", file + ":" + n}
+	default:
+		return []string{file}
+	}
+}
+
+// runEditor runs editor, a command and its arguments, on args and waits for it
+// to be closed.
+func runEditor(editor []string, args ...string) error {
+	cmd := exec.Command(editor[0], append(editor[1:], args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not run %s: %w", strings.Join(editor, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package note
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		visual     string
+		editor     string
+		expected   []string
+	}{
+		{"Configured", "hx", "vim", "nano", []string{"hx"}},
+		{"Visual", "", "code -w", "nano", []string{"code", "-w"}},
+		{"Editor", "", "", " emacs  -nw ", []string{"emacs", "-nw"}},
+		{"Blank", "  ", "", "kak", []string{"kak"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("VISUAL", tc.visual)
+			t.Setenv("EDITOR", tc.editor)
+			if got := Editor(tc.configured); !slices.Equal(got, tc.expected) {
+				t.Errorf("Editor(%q) = %q, expected %q", tc.configured, got, tc.expected)
+			}
+		})
+	}
+	t.Run("Fallback", func(t *testing.T) {
+		t.Setenv("VISUAL", "")
+		t.Setenv("EDITOR", "")
+		t.Setenv("PATH", t.TempDir())
+		if got := Editor(""); !slices.Equal(got, []string{"nvim"}) {
+			t.Errorf("Editor(\"\") = %q, expected nvim", got)
+		}
+	})
+}
+
+func TestLineArgs(t *testing.T) {
+	tests := []struct {
+		editor   string
+		expected []string
+	}{
+		{"nvim", []string{"+12", "notes.md"}},
+		{"/usr/bin/vim", []string{"+12", "notes.md"}},
+		{"emacsclient", []string{"+12", "notes.md"}},
+		{"nano", []string{"+12", "notes.md"}},
+		{"kak", []string{"+12", "notes.md"}},
+		{"hx", []string{"notes.md:12"}},
+		{"code", []string{"--goto", "notes.md:12"}},
+		{"ed", []string{"notes.md"}},
+	}
+	for _, tc := range tests {
+		if got := lineArgs(tc.editor, "notes.md", 12); !slices.Equal(got, tc.expected) {
+			t.Errorf("lineArgs(%q) = %q, expected %q", tc.editor, got, tc.expected)
+		}
+	}
+}
+
+func TestNewestEntryLine(t *testing.T) {
+	data := []byte(`# Todo
+
+## Thu, 15 Oct 2026
+
+- [ ] one
+
+## Sat, 17 Oct 2026
+
+- [ ] two
+
+## Sun, 18 Oct 2026
+
+- [ ] three
+`)
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 9, 0, 0, 0, time.Local) }
+	for now, expected := range map[time.Time]int{
+		day(17): 7,
+		day(19): 11,
+	} {
+		if got := newestEntryLine(data, Todo, now); got != expected {
+			t.Errorf("newestEntryLine(%s) = %d, expected %d", now, got, expected)
+		}
+	}
+	if got := newestEntryLine([]byte("# Notes\n"), Dump, day(17)); got != 1 {
+		t.Errorf("newestEntryLine() of a file without entries = %d, expected 1", got)
+	}
+	fenced := []byte("# Todo\n\n## Sat, 17 Oct 2026\n\n- [ ] fix it\n  ```\n## not a day\n  ```\n")
+	if got := newestEntryLine(fenced, Todo, day(19)); got != 3 {
+		t.Errorf("newestEntryLine() with a heading in a fenced block = %d, expected 3", got)
+	}
+	issues := []byte("# Issues\n\n## #1 First\n\nstatus: Open\n\n## Steps\n\n---\n" +
+		"## #2 Crash on save\n\nstatus: Open\n\n## Steps\n\n```\n## v1.0\n```\n\n---\n")
+	if got := newestEntryLine(issues, Issue, day(17)); got != 10 {
+		t.Errorf("newestEntryLine() of issues with headings in descriptions = %d, expected 10", got)
+	}
+}
+
+func TestOpenNotes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editor is a shell script")
+	}
+	dir := t.TempDir()
+	editor := filepath.Join(dir, "vim")
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n"
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	notesPath := writeFixture(t, "# Issues\n\n## #1 First\n\n---\n## #2 Second\n\n## Steps\n\n---\n")
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.Local)
+	if err := OpenNotes([]string{editor, "-R"}, notesPath, Issue, now); err != nil {
+		t.Fatal(err)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "-R +6 " + notesPath; strings.TrimSpace(string(args)) != expected {
+		t.Errorf("the editor was run with %q, expected %q", args, expected)
+	}
+	missing := filepath.Join(dir, "notes.todo.md")
+	if err = OpenNotes([]string{editor}, missing, Todo, now); err == nil {
+		t.Error("OpenNotes() of a missing file succeeded")
+	}
+}
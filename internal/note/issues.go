@@ -0,0 +1,294 @@
+package note
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// issueHeading matches an issue's "## " heading and captures its ID, if it
+// has been assigned one, and its title.
+var issueHeading = regexp.MustCompile(`^## (?:#(\d+)(?: |$))?(.*)$`)
+
+// SetIssueStatus rewrites the status line of the issue identified by id in
+// the issues file at notesPath.
+func SetIssueStatus(notesPath string, id int, status Status) error {
+	data, _, err := loadIssues(notesPath)
+	if err != nil {
+		return err
+	}
+	i, err := findIssue(data, id)
+	if err != nil {
+		return err
+	}
+	start := i.start
+	lineStart, lineEnd, ok := findLine(data[i.start:i.end], "status:")
+	if !ok {
+		return fmt.Errorf("issue #%d has no status line", id)
+	}
+	var out bytes.Buffer
+	out.Write(data[:start+lineStart])
+	fmt.Fprint(&out, "status: ", status)
+	out.Write(data[start+lineEnd:])
+	return writeFileAtomic(notesPath, out.Bytes())
+}
+
+// AddIssueComment inserts a comment stamped with now at the end of the
+// Comments section of the issue identified by id, wrapped at width as for
+// Note.Width. The rest of the file is left untouched.
+func AddIssueComment(notesPath string, id int, comment string, now time.Time, width int) error {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return errors.New("nothing to comment here")
+	}
+	data, _, err := loadIssues(notesPath)
+	if err != nil {
+		return err
+	}
+	i, err := findIssue(data, id)
+	if err != nil {
+		return err
+	}
+	start := i.start
+	section := data[i.start:i.end]
+	headingStart, headingEnd, ok := findLine(section, commentsHeading)
+	if !ok {
+		return fmt.Errorf("issue #%d has no comments section", id)
+	}
+	sectionEnd := len(section)
+	if sepStart, _, ok := findLine(section[headingEnd:], "---"); ok {
+		sectionEnd = headingEnd + sepStart
+	}
+	// Comments go right after the last non-blank line before the separator,
+	// keeping the list tight and the blank line above the separator intact.
+	insertAt := headingStart + len(bytes.TrimRight(section[headingStart:sectionEnd], "\n"))
+	var entry bytes.Buffer
+	if insertAt < len(section) {
+		insertAt++
+	} else {
+		entry.WriteString("\n")
+	}
+	if insertAt <= headingEnd+1 {
+		entry.WriteString("\n")
+	}
+	fmt.Fprintln(&entry, formatComment(comment, now, columns(width)))
+	var out bytes.Buffer
+	out.Write(data[:start+insertAt])
+	out.Write(entry.Bytes())
+	out.Write(data[start+insertAt:])
+	return writeFileAtomic(notesPath, out.Bytes())
+}
+
+// formatComment renders a comment as a list item wrapped at width, indenting
+// wrapped lines so they stay part of the item.
+func formatComment(comment string, now time.Time, width int) string {
+	if width > 0 {
+		width = max(width-2, 1)
+	}
+	text := wordWrap(fmt.Sprint(now.Format(time.UnixDate), ": ", comment), width)
+	return "- " + strings.ReplaceAll(text, "\n", "\n  ")
+}
+
+// IssueFilter narrows down the issues printed by ListIssues. Zero values match
+// every issue.
+type IssueFilter struct {
+	Status Status
+	Labels []string
+	Since  time.Duration
+}
+
+func (f IssueFilter) matches(i parsedIssue, now time.Time) bool {
+	if f.Status != "" && !strings.EqualFold(string(i.status), string(f.Status)) {
+		return false
+	}
+	for _, label := range f.Labels {
+		hasLabel := func(tag string) bool { return strings.EqualFold(tag, label) }
+		if !slices.ContainsFunc(i.tags, hasLabel) {
+			return false
+		}
+	}
+	return f.Since == 0 || !i.createdAt.Before(now.Add(-f.Since))
+}
+
+// ParseStatus converts a status typed on the command line, such as "closed"
+// or "in-progress", into a Status.
+func ParseStatus(s string) (Status, error) {
+	normalized := strings.NewReplacer("-", "", "_", "", " ", "").Replace(s)
+	for _, status := range []Status{Open, Closed, InProgress} {
+		if strings.EqualFold(normalized, string(status)) {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("unknown status %q, expected open, closed or inprogress", s)
+}
+
+// ListIssues writes the issues in the file at notesPath that match filter to
+// w, either as a table or, if asJSON is set, as a JSON array.
+func ListIssues(
+	w io.Writer,
+	notesPath string,
+	filter IssueFilter,
+	asJSON bool,
+	now time.Time,
+) error {
+	data, _, err := loadIssues(notesPath)
+	if err != nil {
+		return err
+	}
+	var issues []parsedIssue
+	for _, i := range parseIssues(data) {
+		if filter.matches(i, now) {
+			issues = append(issues, i)
+		}
+	}
+	if asJSON {
+		return writeIssuesJSON(w, issues)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tTITLE\tLABELS\tAGE")
+	for _, i := range issues {
+		fmt.Fprintf(
+			tw,
+			"#%d\t%s\t%s\t%s\t%s\n",
+			i.id,
+			i.status,
+			i.title,
+			strings.Join(i.tags, ", "),
+			formatAge(i.createdAt, now),
+		)
+	}
+	return tw.Flush()
+}
+
+func writeIssuesJSON(w io.Writer, issues []parsedIssue) error {
+	type issueJSON struct {
+		CreatedAt   *time.Time `json:"createdAt,omitempty"`
+		Status      Status     `json:"status"`
+		Title       string     `json:"title"`
+		Description string     `json:"description"`
+		Labels      []string   `json:"labels"`
+		Comments    []string   `json:"comments"`
+		ID          int        `json:"id"`
+	}
+	out := make([]issueJSON, 0, len(issues))
+	for _, i := range issues {
+		j := issueJSON{
+			ID:          i.id,
+			Status:      i.status,
+			Title:       i.title,
+			Description: i.description,
+			Labels:      append([]string{}, i.tags...),
+			Comments:    append([]string{}, i.comments...),
+		}
+		if !i.createdAt.IsZero() {
+			j.CreatedAt = &i.createdAt
+		}
+		out = append(out, j)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// formatAge renders the time elapsed since t in the largest whole unit that
+// fits, e.g. "3d" or "5h".
+func formatAge(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	switch d := now.Sub(t); {
+	case d >= 7*24*time.Hour:
+		return fmt.Sprint(int(d/(7*24*time.Hour)), "w")
+	case d >= 24*time.Hour:
+		return fmt.Sprint(int(d/(24*time.Hour)), "d")
+	case d >= time.Hour:
+		return fmt.Sprint(int(d/time.Hour), "h")
+	default:
+		return fmt.Sprint(int(d/time.Minute), "m")
+	}
+}
+
+// loadIssues reads the issues file at notesPath and assigns IDs to any issues
+// that do not have one yet, writing them back to the file. It returns the
+// numbered contents along with the ID the next new issue should get.
+func loadIssues(notesPath string) ([]byte, int, error) {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	numbered, next := numberIssues(data)
+	if !bytes.Equal(numbered, data) {
+		if err = writeFileAtomic(notesPath, numbered); err != nil {
+			return nil, 0, err
+		}
+	}
+	return numbered, next, nil
+}
+
+// numberIssues assigns IDs, in file order, to the issue headings in data that
+// do not have one. New IDs continue from the highest ID already in use so
+// that existing IDs never change. It returns the updated data and the next
+// unused ID.
+func numberIssues(data []byte) ([]byte, int) {
+	lines := strings.SplitAfter(string(data), "\n")
+	headings := issueHeadings(lines)
+	maxID := 0
+	for _, h := range headings {
+		if id, _, _ := parseIssueHeading(lines[h]); id > maxID {
+			maxID = id
+		}
+	}
+	for _, h := range headings {
+		if id, _, _ := parseIssueHeading(lines[h]); id == 0 {
+			maxID++
+			lines[h] = fmt.Sprintf("## #%d %s", maxID, strings.TrimPrefix(lines[h], "## "))
+		}
+	}
+	return []byte(strings.Join(lines, "")), maxID + 1
+}
+
+// parseIssueHeading reports whether line is an issue heading, returning its ID
+// (zero if it has none) and title.
+func parseIssueHeading(line string) (int, string, bool) {
+	m := issueHeading.FindStringSubmatch(strings.TrimSuffix(line, "\n"))
+	if m == nil {
+		return 0, "", false
+	}
+	id, _ := strconv.Atoi(m[1])
+	return id, m[2], true
+}
+
+// findIssue returns the issue with the given id in data.
+func findIssue(data []byte, id int) (parsedIssue, error) {
+	for _, i := range parseIssues(data) {
+		if i.id == id {
+			return i, nil
+		}
+	}
+	return parsedIssue{}, fmt.Errorf("could not find issue #%d", id)
+}
+
+// findLine returns the range of the first line in data that starts with
+// prefix, excluding its trailing newline.
+func findLine(data []byte, prefix string) (int, int, bool) {
+	for offset := 0; offset < len(data); {
+		lineEnd := len(data)
+		if next := bytes.IndexByte(data[offset:], '\n'); next >= 0 {
+			lineEnd = offset + next
+		}
+		if bytes.HasPrefix(data[offset:lineEnd], []byte(prefix)) {
+			return offset, lineEnd, true
+		}
+		offset = lineEnd + 1
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,340 @@
+package note
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func issuesFixture(first, second Status) string {
+	return fmt.Sprintf(`# Issues
+
+## #1 Login fails on retry
+
+createdAt: Sat Jan  1 00:00:00 UTC 2022
+status: %s
+labels: bug
+
+The login feature fails when...
+
+### Comments
+
+---
+## #2 Dark mode
+
+createdAt: Sun Jan  2 00:00:00 UTC 2022
+status: %s
+labels: enhancement
+
+Add a dark theme.
+
+### Comments
+
+---`, first, second)
+}
+
+func writeFixture(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notes.issue.md")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSetIssueStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       int
+		status   Status
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "CloseFirst",
+			id:       1,
+			status:   Closed,
+			expected: issuesFixture(Closed, Open),
+		},
+		{
+			name:     "StartLast",
+			id:       2,
+			status:   InProgress,
+			expected: issuesFixture(Open, InProgress),
+		},
+		{
+			name:    "MissingIssue",
+			id:      3,
+			status:  Closed,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidID",
+			id:      0,
+			status:  Closed,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, issuesFixture(Open, Open))
+			err := SetIssueStatus(path, tc.id, tc.status)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SetIssueStatus() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("SetIssueStatus() wrote:\n%s\nexpected:\n%s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNumberIssues(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		nextID   int
+	}{
+		{
+			name:     "Empty",
+			input:    "# Issues\n",
+			expected: "# Issues\n",
+			nextID:   1,
+		},
+		{
+			name:     "Unnumbered",
+			input:    "# Issues\n\n## First\n\n### Comments\n\n---\n## Second\n",
+			expected: "# Issues\n\n## #1 First\n\n### Comments\n\n---\n## #2 Second\n",
+			nextID:   3,
+		},
+		{
+			name:     "Mixed",
+			input:    "# Issues\n\n## #4 First\n\n---\n## Second\n\n---\n## #2 Third",
+			expected: "# Issues\n\n## #4 First\n\n---\n## #5 Second\n\n---\n## #2 Third",
+			nextID:   6,
+		},
+		{
+			name:     "EmptyTitle",
+			input:    "# Issues\n\n## \n\n---\n## #7\n",
+			expected: "# Issues\n\n## #8 \n\n---\n## #7\n",
+			nextID:   9,
+		},
+		{
+			name: "HeadingsInDescriptions",
+			input: "# Issues\n\n## First\n\nstatus: Open\n\n```md\n## v1.0\n```\n\n## Steps\n\n" +
+				"### Comments\n\n---\n## Second\n",
+			expected: "# Issues\n\n## #1 First\n\nstatus: Open\n\n```md\n## v1.0\n```\n\n## Steps\n\n" +
+				"### Comments\n\n---\n## #2 Second\n",
+			nextID: 3,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, next := numberIssues([]byte(tc.input))
+			if string(got) != tc.expected {
+				t.Errorf("numberIssues() = %q, expected %q", got, tc.expected)
+			}
+			if next != tc.nextID {
+				t.Errorf("numberIssues() next = %d, expected %d", next, tc.nextID)
+			}
+		})
+	}
+}
+
+func TestSetIssueStatusNumbersExistingIssues(t *testing.T) {
+	unnumbered := strings.NewReplacer("## #1 ", "## ", "## #2 ", "## ")
+	path := writeFixture(t, unnumbered.Replace(issuesFixture(Open, Open)))
+	if err := SetIssueStatus(path, 2, Closed); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := issuesFixture(Open, Closed); string(got) != expected {
+		t.Errorf("SetIssueStatus() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestAddIssueComment(t *testing.T) {
+	now := time.Date(2022, time.January, 3, 10, 30, 0, 0, time.UTC)
+	stamp := "- Mon Jan  3 10:30:00 UTC 2022: "
+	tests := []struct {
+		name     string
+		input    string
+		id       int
+		comments []string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "FirstComment",
+			input:    issuesFixture(Open, Open),
+			id:       1,
+			comments: []string{"Reproduced on staging."},
+			expected: strings.Replace(
+				issuesFixture(Open, Open),
+				"### Comments\n\n---",
+				"### Comments\n\n"+stamp+"Reproduced on staging.\n\n---",
+				1,
+			),
+		},
+		{
+			name:     "AppendsAfterExistingComments",
+			input:    issuesFixture(Open, Open),
+			id:       2,
+			comments: []string{"first", "second"},
+			expected: issuesFixture(Open, Open)[:strings.LastIndex(issuesFixture(Open, Open), "---")] +
+				stamp + "first\n" + stamp + "second\n\n---",
+		},
+		{
+			name:     "NoSeparator",
+			input:    "# Issues\n\n## #1 Title\n\nstatus: Open\n\n### Comments",
+			id:       1,
+			comments: []string{"a", "b"},
+			expected: "# Issues\n\n## #1 Title\n\nstatus: Open\n\n### Comments\n\n" +
+				stamp + "a\n" + stamp + "b\n",
+		},
+		{
+			name:     "WrapsLongComments",
+			input:    "# Issues\n\n## #1 Title\n\n### Comments\n\n---\n",
+			id:       1,
+			comments: []string{strings.Repeat("word ", 16)},
+			expected: "# Issues\n\n## #1 Title\n\n### Comments\n\n" + stamp +
+				strings.Repeat("word ", 8) + "word\n  " + strings.Repeat("word ", 6) + "word\n\n---\n",
+		},
+		{
+			name:     "MissingIssue",
+			input:    issuesFixture(Open, Open),
+			id:       5,
+			comments: []string{"text"},
+			wantErr:  true,
+		},
+		{
+			name:     "EmptyComment",
+			input:    issuesFixture(Open, Open),
+			id:       1,
+			comments: []string{"  "},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, tc.input)
+			for _, comment := range tc.comments {
+				err := AddIssueComment(path, tc.id, comment, now, 0)
+				if (err != nil) != tc.wantErr {
+					t.Fatalf("AddIssueComment() error = %v, wantErr %v", err, tc.wantErr)
+				}
+			}
+			if tc.wantErr {
+				return
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("AddIssueComment() wrote:\n%q\nexpected:\n%q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestListIssues(t *testing.T) {
+	now := time.Date(2022, time.January, 3, 0, 0, 0, 0, time.UTC)
+	fixture := strings.Replace(
+		issuesFixture(Closed, InProgress),
+		"labels: enhancement",
+		"labels: enhancement, ui",
+		1,
+	)
+	tests := []struct {
+		name     string
+		filter   IssueFilter
+		expected string
+	}{
+		{
+			name:   "All",
+			filter: IssueFilter{},
+			expected: "ID  STATUS      TITLE                 LABELS           AGE\n" +
+				"#1  Closed      Login fails on retry  bug              2d\n" +
+				"#2  InProgress  Dark mode             enhancement, ui  1d\n",
+		},
+		{
+			name:   "Status",
+			filter: IssueFilter{Status: Closed},
+			expected: "ID  STATUS  TITLE                 LABELS  AGE\n" +
+				"#1  Closed  Login fails on retry  bug     2d\n",
+		},
+		{
+			name:   "Labels",
+			filter: IssueFilter{Labels: []string{"UI", "enhancement"}},
+			expected: "ID  STATUS      TITLE      LABELS           AGE\n" +
+				"#2  InProgress  Dark mode  enhancement, ui  1d\n",
+		},
+		{
+			name:     "Since",
+			filter:   IssueFilter{Since: 12 * time.Hour},
+			expected: "ID  STATUS  TITLE  LABELS  AGE\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, fixture)
+			var out strings.Builder
+			if err := ListIssues(&out, path, tc.filter, false, now); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.expected {
+				t.Errorf("ListIssues() printed:\n%s\nexpected:\n%s", out.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestListIssuesJSON(t *testing.T) {
+	path := writeFixture(t, issuesFixture(Open, Closed))
+	var out bytes.Buffer
+	err := ListIssues(&out, path, IssueFilter{Status: Open}, true, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []map[string]any
+	if err = json.Unmarshal(out.Bytes(), &issues); err != nil {
+		t.Fatalf("ListIssues() printed invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(issues) != 1 || issues[0]["id"] != float64(1) || issues[0]["status"] != "Open" ||
+		issues[0]["description"] != "The login feature fails when..." {
+		t.Errorf("ListIssues() printed %s", out.String())
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	for input, expected := range map[string]Status{
+		"open":        Open,
+		"Closed":      Closed,
+		"inprogress":  InProgress,
+		"in-progress": InProgress,
+	} {
+		if got, err := ParseStatus(input); err != nil || got != expected {
+			t.Errorf("ParseStatus(%q) = %q, %v, expected %q", input, got, err, expected)
+		}
+	}
+	if _, err := ParseStatus("done"); err == nil {
+		t.Error("ParseStatus(\"done\") expected an error")
+	}
+}
@@ -0,0 +1,335 @@
+package note
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// MarkStrike marks dead links by striking their titles through.
+	MarkStrike = "strike"
+	// MarkTag marks dead links by tagging them #dead.
+	MarkTag = "tag"
+)
+
+const (
+	// maxRedirects is how many redirects a link may go through before it is
+	// reported as broken.
+	maxRedirects = 10
+	deadTag      = "dead"
+)
+
+// hostInterval is how long to wait between two requests to the same host.
+var hostInterval = 500 * time.Millisecond
+
+// LinkCheckOptions configures CheckBookmarks.
+type LinkCheckOptions struct {
+	// Mark is how dead links are marked in the file: MarkStrike, MarkTag, or
+	// empty to leave the file alone.
+	Mark string
+	// Timeout bounds each request. Zero means no limit.
+	Timeout time.Duration
+	// FixRedirects rewrites links that are permanently redirected to where
+	// they end up.
+	FixRedirects bool
+}
+
+// linkStatus is the outcome of checking a link.
+type linkStatus struct {
+	err error
+	// final is where the link ends up after following redirects.
+	final string
+	// status is the HTTP status of the last response.
+	status int
+	// redirected is set if the link was redirected, and permanent if every
+	// redirect on the way was permanent.
+	redirected bool
+	permanent  bool
+}
+
+func (s linkStatus) dead() bool {
+	return s.err == nil && s.status >= 400
+}
+
+// CheckBookmarks checks every link in the bookmarks file at notesPath and
+// writes a report of the dead, redirected and unreachable ones to w. Links
+// are checked concurrently, with a HEAD request that falls back to GET, and
+// requests to the same host are spaced out. Depending on opts, dead links are
+// marked and permanent redirects rewritten in place.
+func CheckBookmarks(
+	ctx context.Context,
+	w io.Writer,
+	notesPath string,
+	opts LinkCheckOptions,
+) error {
+	if opts.Mark != "" && opts.Mark != MarkStrike && opts.Mark != MarkTag {
+		return fmt.Errorf("unknown mark %q, expected %s or %s", opts.Mark, MarkStrike, MarkTag)
+	}
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return err
+	}
+	bookmarks := parseBookmarks(data)
+	var urls []string
+	for _, b := range bookmarks {
+		if isWebURL(b.url) && !slices.Contains(urls, b.url) {
+			urls = append(urls, b.url)
+		}
+	}
+	checker := linkChecker{
+		client: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		limiter: hostLimiter{interval: hostInterval, next: map[string]time.Time{}},
+		timeout: opts.Timeout,
+	}
+	statuses := make([]linkStatus, len(urls))
+	runConcurrently(len(urls), func(i int) {
+		statuses[i] = checker.check(ctx, urls[i])
+	})
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	results := make(map[string]linkStatus, len(urls))
+	for i, u := range urls {
+		results[u] = statuses[i]
+	}
+	if err = writeLinkReport(w, urls, results); err != nil {
+		return err
+	}
+	if opts.Mark == "" && !opts.FixRedirects {
+		return nil
+	}
+	return markBookmarks(notesPath, data, bookmarks, results, opts)
+}
+
+func writeLinkReport(w io.Writer, urls []string, results map[string]linkStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tURL\tDETAIL")
+	problems := 0
+	for _, u := range urls {
+		s := results[u]
+		switch {
+		case s.err != nil:
+			kind := "unreachable"
+			if errors.Is(s.err, context.DeadlineExceeded) {
+				kind = "timeout"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%v\n", kind, u, s.err)
+		case s.dead():
+			fmt.Fprintf(tw, "dead\t%s\t%d %s\n", u, s.status, http.StatusText(s.status))
+		case s.redirected:
+			kind := "moved"
+			if s.permanent {
+				kind = "moved permanently"
+			}
+			fmt.Fprintf(tw, "redirect\t%s\t%s to %s\n", u, kind, s.final)
+		default:
+			continue
+		}
+		problems++
+	}
+	fmt.Fprintf(tw, "\nchecked %d links, %d need attention\n", len(urls), problems)
+	return tw.Flush()
+}
+
+// markBookmarks rewrites the bookmarks in data, read from notesPath, whose
+// links are dead or permanently redirected, as asked for in opts.
+func markBookmarks(
+	notesPath string,
+	data []byte,
+	bookmarks []parsedBookmark,
+	results map[string]linkStatus,
+	opts LinkCheckOptions,
+) error {
+	current, err := os.ReadFile(notesPath)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, data) {
+		return errors.New("the bookmarks file changed while checking, try again")
+	}
+	var out bytes.Buffer
+	prev := 0
+	for _, b := range bookmarks {
+		s, ok := results[b.url]
+		if !ok {
+			continue
+		}
+		entry := string(data[b.start:b.end])
+		switch {
+		case s.dead() && opts.Mark == MarkStrike:
+			entry = strikeBookmark(entry)
+		case s.dead() && opts.Mark == MarkTag:
+			entry = tagBookmark(entry, b.bookmark, deadTag)
+		case s.redirected && s.permanent && s.status < 400 && opts.FixRedirects:
+			entry = relinkBookmark(entry, s.final)
+		}
+		out.Write(data[prev:b.start])
+		out.WriteString(entry)
+		prev = b.end
+	}
+	out.Write(data[prev:])
+	if bytes.Equal(out.Bytes(), data) {
+		return nil
+	}
+	return writeFileAtomic(notesPath, out.Bytes())
+}
+
+// strikeBookmark strikes the title of the bookmark in entry through.
+func strikeBookmark(entry string) string {
+	m := bookmarkLink.FindStringIndex(entry)
+	title := entry[1:m[0]]
+	if strings.HasPrefix(title, "~~") {
+		return entry
+	}
+	return fmt.Sprint("[~~", title, "~~", entry[m[0]:])
+}
+
+// tagBookmark adds tag to the tags line of the bookmark b in entry, adding
+// the line if there is none.
+func tagBookmark(entry string, b bookmark, tag string) string {
+	if slices.Contains(b.tags, tag) {
+		return entry
+	}
+	var tags []string
+	for _, t := range b.tags {
+		tags = append(tags, fmt.Sprint("**#", t, "**"))
+	}
+	tags = append(tags, fmt.Sprint("**#", tag, "**"))
+	tagsLine := fmt.Sprintf("tags: %s  ", strings.Join(tags, " "))
+	linkEnd := bookmarkLink.FindStringIndex(entry)[1]
+	if !strings.HasSuffix(entry[:linkEnd], "\n") {
+		return entry[:linkEnd] + "\n" + tagsLine
+	}
+	rest := entry[linkEnd:]
+	if strings.HasPrefix(rest, "tags:") {
+		var ok bool
+		if _, rest, ok = strings.Cut(rest, "\n"); !ok {
+			return entry[:linkEnd] + tagsLine
+		}
+	}
+	return entry[:linkEnd] + tagsLine + "\n" + rest
+}
+
+// relinkBookmark points the bookmark in entry to target.
+func relinkBookmark(entry, target string) string {
+	m := bookmarkLink.FindStringSubmatchIndex(entry)
+	return entry[:m[2]] + target + entry[m[3]:]
+}
+
+type linkChecker struct {
+	client  *http.Client
+	limiter hostLimiter
+	timeout time.Duration
+}
+
+// check follows link through its redirects and reports where it ends up.
+func (c *linkChecker) check(ctx context.Context, link string) linkStatus {
+	s := linkStatus{final: link, permanent: true}
+	for range maxRedirects {
+		resp, err := c.request(ctx, s.final)
+		if err != nil {
+			s.err = err
+			return s
+		}
+		location := resp.Header.Get("Location")
+		s.status = resp.StatusCode
+		if s.status < 300 || s.status > 399 || location == "" {
+			s.permanent = s.permanent && s.redirected
+			return s
+		}
+		next, err := url.Parse(location)
+		if err != nil {
+			s.err = fmt.Errorf("invalid redirect to %q", location)
+			return s
+		}
+		s.final = resp.Request.URL.ResolveReference(next).String()
+		s.redirected = true
+		s.permanent = s.permanent &&
+			(s.status == http.StatusMovedPermanently || s.status == http.StatusPermanentRedirect)
+	}
+	s.err = fmt.Errorf("stopped after %d redirects", maxRedirects)
+	return s
+}
+
+// request asks for link with HEAD, falling back to GET for servers that do
+// not answer HEAD requests properly.
+func (c *linkChecker) request(ctx context.Context, link string) (*http.Response, error) {
+	resp, err := c.do(ctx, http.MethodHead, link)
+	switch {
+	case err == nil && resp.StatusCode < 400:
+		return resp, nil
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return nil, err
+	}
+	return c.do(ctx, http.MethodGet, link)
+}
+
+func (c *linkChecker) do(ctx context.Context, method, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.limiter.wait(ctx, req.URL.Host); err != nil {
+		return nil, err
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	// Only the status and headers matter, so the body is never read.
+	resp.Body.Close()
+	return resp, nil
+}
+
+// hostLimiter spaces out requests to the same host by interval.
+type hostLimiter struct {
+	next     map[string]time.Time
+	interval time.Duration
+	mu       sync.Mutex
+}
+
+// wait blocks until a request to host may be made, or ctx is done.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,177 @@
+package note
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func linkCheckServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/hop":
+			http.Redirect(w, r, "/moved", http.StatusPermanentRedirect)
+		case "/temporary":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/slow":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+const linkCheckFixture = `# Bookmarks
+
+## Fri, 16 Oct 2026
+
+[Fine](URL/ok)\
+tags:
+
+[No HEAD](URL/no-head)\
+tags:
+
+[Gone](URL/gone)\
+tags: **#go**
+Was a good read
+
+[Moved](URL/hop)\
+tags:
+
+[Temporary](URL/temporary)\
+tags:
+
+[Slow](URL/slow)\
+tags:
+`
+
+func TestCheckBookmarks(t *testing.T) {
+	defer func(interval time.Duration) { hostInterval = interval }(hostInterval)
+	hostInterval = 0
+	server := linkCheckServer(t)
+	placeholders := strings.NewReplacer("URL", server.URL)
+	tests := []struct {
+		name     string
+		opts     LinkCheckOptions
+		expected string
+	}{
+		{
+			name:     "ReportOnly",
+			expected: linkCheckFixture,
+		},
+		{
+			name: "Strike",
+			opts: LinkCheckOptions{Mark: MarkStrike},
+			expected: strings.Replace(
+				linkCheckFixture,
+				"[Gone](URL/gone)",
+				"[~~Gone~~](URL/gone)",
+				1,
+			),
+		},
+		{
+			name: "TagAndFixRedirects",
+			opts: LinkCheckOptions{Mark: MarkTag, FixRedirects: true},
+			expected: strings.NewReplacer(
+				"tags: **#go**\n", "tags: **#go** **#dead**  \n",
+				"[Moved](URL/hop)", "[Moved](URL/ok)",
+			).Replace(linkCheckFixture),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, placeholders.Replace(linkCheckFixture))
+			tc.opts.Timeout = 100 * time.Millisecond
+			var out strings.Builder
+			if err := CheckBookmarks(context.Background(), &out, path, tc.opts); err != nil {
+				t.Fatal(err)
+			}
+			report := out.String()
+			for _, line := range []string{
+				"dead      " + server.URL + "/gone       404 Not Found",
+				"redirect  " + server.URL + "/hop        moved permanently to " + server.URL + "/ok",
+				"redirect  " + server.URL + "/temporary  moved to " + server.URL + "/ok",
+				"timeout   " + server.URL + "/slow",
+				"checked 6 links, 4 need attention",
+			} {
+				if !strings.Contains(report, line) {
+					t.Errorf("CheckBookmarks() report is missing %q:\n%s", line, report)
+				}
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := placeholders.Replace(tc.expected); string(got) != expected {
+				t.Errorf("CheckBookmarks() wrote:\n%s\nexpected:\n%s", got, expected)
+			}
+		})
+	}
+}
+
+func TestTagBookmark(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		expected string
+	}{
+		{
+			name:     "NoTags",
+			entry:    "[Title](https://example.com)\\\ntags:\n",
+			expected: "[Title](https://example.com)\\\ntags: **#dead**  \n",
+		},
+		{
+			name:     "NoTagsLine",
+			entry:    "[Title](https://example.com)\\\nA description\n",
+			expected: "[Title](https://example.com)\\\ntags: **#dead**  \nA description\n",
+		},
+		{
+			name:     "EndOfFile",
+			entry:    "[Title](https://example.com)\\",
+			expected: "[Title](https://example.com)\\\ntags: **#dead**  ",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := parseBookmarks([]byte(tc.entry))[0]
+			if got := tagBookmark(tc.entry, b.bookmark, deadTag); got != tc.expected {
+				t.Errorf("tagBookmark() = %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	l := hostLimiter{interval: 50 * time.Millisecond, next: map[string]time.Time{}}
+	start := time.Now()
+	for _, host := range []string{"a", "b", "a", "a"} {
+		if err := l.wait(context.Background(), host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("three requests to one host took %s, expected at least 100ms", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx, "a"); err == nil {
+		t.Error("wait() on a cancelled context expected an error")
+	}
+}
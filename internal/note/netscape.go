@@ -0,0 +1,219 @@
+package note
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const netscapeHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+`
+
+// netscapeBookmark is a link read from a Netscape bookmark file.
+type netscapeBookmark struct {
+	addedAt time.Time
+	bookmark
+}
+
+// ImportBookmarks adds the links in the Netscape bookmark file read from r,
+// as exported by browsers, to the bookmarks file at notesPath. The folders a
+// link is in become its tags, and it is filed under the day it was added on,
+// or under now's if the file does not say. Links that are already bookmarked
+// are skipped. Descriptions are wrapped at width, as for Note.Width. It
+// returns how many bookmarks were imported and skipped.
+func ImportBookmarks(notesPath string, r io.Reader, now time.Time, width int) (int, int, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	links := readNetscapeList(doc, nil, now, columns(width))
+	data, err := readBookmarksFile(notesPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	seen := map[string]bool{}
+	for _, b := range parseBookmarks(data) {
+		seen[normalizeURL(b.url)] = true
+	}
+	var days []time.Time
+	entries := map[time.Time][]string{}
+	skipped := 0
+	for _, b := range links {
+		if seen[b.url] {
+			skipped++
+			continue
+		}
+		seen[b.url] = true
+		day := startOfDay(b.addedAt)
+		if _, ok := entries[day]; !ok {
+			days = append(days, day)
+		}
+		entries[day] = append(entries[day], b.format(b.url, pageMeta{title: b.title}))
+	}
+	if len(days) == 0 {
+		return 0, skipped, nil
+	}
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+	for _, day := range days {
+		data = addBookmarksToDay(data, day, entries[day])
+	}
+	return len(links) - skipped, skipped, writeFileAtomic(notesPath, data)
+}
+
+// readNetscapeList returns the links in the bookmark list n, tagged with the
+// folders they are in.
+func readNetscapeList(
+	n *html.Node,
+	folders []string,
+	now time.Time,
+	width int,
+) []netscapeBookmark {
+	var links []netscapeBookmark
+	// A folder is an H3 heading followed by the list of its contents.
+	var folder string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.DataAtom {
+		case atom.H3:
+			folder = ""
+			// The toolbar and unsorted folders are where browsers put
+			// bookmarks, not folders anyone made.
+			if attr(c, "personal_toolbar_folder") == "" &&
+				attr(c, "unfiled_bookmarks_folder") == "" {
+				folder = strings.Join(strings.Fields(textContent(c)), "-")
+			}
+		case atom.Dl:
+			nested := folders
+			if folder != "" {
+				nested = append(slices.Clip(folders), folder)
+			}
+			links = append(links, readNetscapeList(c, nested, now, width)...)
+			folder = ""
+		case atom.A:
+			if b, ok := readNetscapeLink(c, folders, now, width); ok {
+				links = append(links, b)
+			}
+		default:
+			links = append(links, readNetscapeList(c, folders, now, width)...)
+		}
+	}
+	return links
+}
+
+// readNetscapeLink reads the link a, described by the DD element that follows
+// the DT it is in, if any, which is wrapped at width.
+func readNetscapeLink(
+	a *html.Node,
+	folders []string,
+	now time.Time,
+	width int,
+) (netscapeBookmark, bool) {
+	href := strings.TrimSpace(attr(a, "href"))
+	// Links with spaces or parentheses do not fit in a markdown link, which
+	// rules out bookmarklets, and place: links are Firefox's saved searches.
+	if href == "" || strings.ContainsAny(href, " \t\n()") || strings.HasPrefix(href, "place:") {
+		return netscapeBookmark{}, false
+	}
+	b := netscapeBookmark{addedAt: now}
+	b.url = normalizeURL(href)
+	b.title = firstNonBlank(textContent(a), b.url)
+	if seconds, err := strconv.ParseInt(attr(a, "add_date"), 10, 64); err == nil && seconds > 0 {
+		b.addedAt = time.Unix(seconds, 0)
+	}
+	b.tags = slices.Clone(folders)
+	b.merge(bookmark{tags: strings.Split(attr(a, "tags"), ",")})
+	if a.Parent != nil && a.Parent.DataAtom == atom.Dt {
+		dd := a.Parent.NextSibling
+		for dd != nil && dd.Type != html.ElementNode {
+			dd = dd.NextSibling
+		}
+		if dd != nil && dd.DataAtom == atom.Dd {
+			b.description = netscapeDescription(dd, width)
+		}
+	}
+	return b, true
+}
+
+// netscapeDescription returns the text of dd, wrapped line by line at width.
+func netscapeDescription(dd *html.Node, width int) string {
+	var sb strings.Builder
+	for c := dd.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+	var lines []string
+	for _, l := range strings.Split(sb.String(), "\n") {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			lines = append(lines, wordWrap(l, width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExportBookmarks writes the bookmarks in the file at notesPath to w as a
+// Netscape bookmark file that browsers can import. Bookmarks are put in a
+// folder named after their first tag, with all of their tags kept in the TAGS
+// attribute, and dated by the day they were filed under.
+func ExportBookmarks(w io.Writer, notesPath string) error {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return err
+	}
+	var folders []string
+	byFolder := map[string][]parsedBookmark{}
+	for _, b := range parseBookmarks(data) {
+		var folder string
+		if len(b.tags) > 0 {
+			folder = b.tags[0]
+		}
+		if _, ok := byFolder[folder]; !ok && folder != "" {
+			folders = append(folders, folder)
+		}
+		byFolder[folder] = append(byFolder[folder], b)
+	}
+	var sb strings.Builder
+	sb.WriteString(netscapeHeader)
+	sb.WriteString("<DL><p>\n")
+	for _, folder := range folders {
+		fmt.Fprintf(&sb, "    <DT><H3>%s</H3>\n", html.EscapeString(folder))
+		sb.WriteString("    <DL><p>\n")
+		writeNetscapeLinks(&sb, "        ", byFolder[folder])
+		sb.WriteString("    </DL><p>\n")
+	}
+	writeNetscapeLinks(&sb, "    ", byFolder[""])
+	sb.WriteString("</DL><p>\n")
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+func writeNetscapeLinks(sb *strings.Builder, indent string, bookmarks []parsedBookmark) {
+	for _, b := range bookmarks {
+		fmt.Fprintf(sb, "%s<DT><A HREF=\"%s\"", indent, html.EscapeString(b.url))
+		if !b.section.date.IsZero() {
+			fmt.Fprintf(sb, " ADD_DATE=\"%d\"", b.section.date.Unix())
+		}
+		if len(b.tags) > 0 {
+			fmt.Fprintf(sb, " TAGS=\"%s\"", html.EscapeString(strings.Join(b.tags, ",")))
+		}
+		fmt.Fprintf(sb, ">%s</A>\n", html.EscapeString(b.title))
+		if b.description != "" {
+			fmt.Fprintf(sb, "%s<DD>%s\n", indent, html.EscapeString(b.description))
+		}
+	}
+}
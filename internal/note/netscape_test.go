@@ -0,0 +1,130 @@
+package note
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportBookmarks(t *testing.T) {
+	unix := func(day int) string {
+		return strconv.FormatInt(time.Date(2026, 10, day, 9, 30, 0, 0, time.Local).Unix(), 10)
+	}
+	exported := strings.NewReplacer("D15", unix(15), "D17", unix(17)).Replace(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="D15" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><H3 ADD_DATE="D15">Go Stuff</H3>
+        <DL><p>
+            <DT><A HREF="https://go.dev/blog/?utm_source=x" ADD_DATE="D15">The Go Blog</A>
+            <DD>Posts about Go &amp; its tools
+            <DT><H3>Web</H3>
+            <DL><p>
+                <DT><A HREF="https://example.com/other" ADD_DATE="D17" TAGS="later">Other</A>
+            </DL><p>
+        </DL><p>
+        <DT><A HREF="https://example.com/post" ADD_DATE="D15">Already there</A>
+        <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+    </DL><p>
+    <DT><A HREF="https://example.com/undated">Undated</A>
+</DL><p>
+`)
+	path := writeFixture(t, `# Bookmarks
+
+## Thu, 15 Oct 2026
+
+[Example](https://example.com/post)\
+tags: **#go**
+First read
+
+## Sat, 17 Oct 2026
+
+[Today](https://example.com/today)\
+tags:
+`)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	imported, skipped, err := ImportBookmarks(path, strings.NewReader(exported), now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 3 || skipped != 1 {
+		t.Errorf("ImportBookmarks() = %d, %d, expected 3 imported and 1 skipped", imported, skipped)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Bookmarks
+
+## Thu, 15 Oct 2026
+
+[Example](https://example.com/post)\
+tags: **#go**
+First read
+
+[The Go Blog](https://go.dev/blog)\
+tags: **#Go-Stuff**  
+Posts about Go & its tools
+
+## Sat, 17 Oct 2026
+
+[Today](https://example.com/today)\
+tags:
+
+[Other](https://example.com/other)\
+tags: **#Go-Stuff** **#Web** **#later**  
+
+## Sun, 18 Oct 2026
+
+[Undated](https://example.com/undated)\
+tags:
+`
+	if string(got) != expected {
+		t.Errorf("ImportBookmarks() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestExportBookmarks(t *testing.T) {
+	path := writeFixture(t, duplicatesFixture)
+	var exported strings.Builder
+	if err := ExportBookmarks(&exported, path); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"<DT><H3>go</H3>",
+		`<DT><A HREF="https://example.com/post" ADD_DATE="` +
+			strconv.FormatInt(time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local).Unix(), 10) +
+			`" TAGS="go">Example</A>`,
+		"<DD>First read",
+		`    <DT><A HREF="https://example.com/other" ADD_DATE=`,
+	} {
+		if !strings.Contains(exported.String(), line) {
+			t.Errorf("ExportBookmarks() is missing %q:\n%s", line, exported.String())
+		}
+	}
+
+	// Importing the export into an empty file brings back every bookmark.
+	copied := filepath.Join(t.TempDir(), "notes.bookmark.md")
+	imported, skipped, err := ImportBookmarks(copied, strings.NewReader(exported.String()), time.Now(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 || skipped != 2 {
+		t.Errorf("ImportBookmarks() = %d, %d, expected 2 imported and 2 skipped", imported, skipped)
+	}
+	data, err := os.ReadFile(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range parseBookmarks(data) {
+		if b.section.heading == "" || len(b.tags) == 0 && b.title != "Other" {
+			t.Errorf("ImportBookmarks() lost the date or tags of %+v", b)
+		}
+	}
+}
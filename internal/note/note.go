@@ -2,6 +2,7 @@
 package note
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -15,15 +16,28 @@ import (
 
 // Note struct  
 type Note struct {
-	Status      Status
-	Content     string
-	Description string
-	NotesPath   string
-	Title       string
-	Type        string
-	Tags        []string
+	Due          time.Time
+	Status       Status
+	Content      string
+	Description  string
+	Editor       string
+	NotesPath    string
+	Title        string
+	Type         string
+	Tags         []string
+	Priority     Priority
+	FetchTimeout time.Duration
+	// Width is the width text is wrapped at, zero meaning the default of 80
+	// columns and NoWrap not wrapping it.
+	Width       int
+	Archive     bool
 	EditFile    bool
 	HidePreview bool
+	Rollover    bool
+	// Verbatim keeps content that spans several lines as it is, in a fenced
+	// block, rather than wrapping and sentence casing it, as for output
+	// piped in.
+	Verbatim bool
 }
 
 // New function  
@@ -56,14 +70,31 @@ func (n Note) validate() error {
 }
 
 // Note method  
-func (n Note) Note() error {
+func (n Note) Note(ctx context.Context) error {
+	if n.EditFile {
+		var err error
+		if n, err = n.compose(Editor(n.Editor)); err != nil {
+			return err
+		}
+	}
 	note := n.getNoteType()
 	if note == nil {
 		return nil
 	}
 	setupFile(n.NotesPath, note.label())
-	maybeOpenEditor(n.EditFile, n.NotesPath, "nvim")
-	markdown, err := note.toMarkdown(n.Content)
+	if i, ok := note.(*issue); ok {
+		_, nextID, err := loadIssues(n.NotesPath)
+		if err != nil {
+			return err
+		}
+		i.id = nextID
+	}
+	if n.Rollover && n.Type == Todo {
+		if err := rolloverOnNewDay(n.NotesPath, time.Now()); err != nil {
+			return err
+		}
+	}
+	markdown, err := note.toMarkdown(ctx, n.Content)
 	if err != nil {
 		return err
 	}
@@ -92,16 +123,25 @@ func (n Note) getNoteType() noteType {
 	var note noteType
 	switch n.Type {
 	case Bookmark:
-		note = bookmark{
+		b := bookmark{
 			description: n.Description,
 			tags:        n.Tags,
+			timeout:     n.FetchTimeout,
+			width:       n.Width,
+		}
+		if n.Archive {
+			b.archiveTo = n.NotesPath
 		}
+		note = b
 	case Dump:
-		note = notes{}
+		note = notes{verbatim: n.Verbatim, width: n.Width}
 	case Todo:
-		note = todo{}
+		note = todo{due: n.Due, priority: n.Priority, verbatim: n.Verbatim, width: n.Width}
 	case Issue:
-		note = newIssue(n.Title, n.Description, n.Tags, time.Now())
+		i := newIssue(n.Title, n.Description, n.Tags, time.Now())
+		i.verbatim = n.Verbatim
+		i.width = n.Width
+		note = i
 	default:
 		fmt.Fprintln(os.Stdout, "nothing to do")
 		return nil
@@ -115,28 +155,34 @@ func setupFile(filepath, label string) {
 		os.MkdirAll(dpath, 0o755)
 	}
 	if _, err := os.Stat(filepath); os.IsNotExist(err) {
-		heading := fmt.Sprintf("# %s\n", sentenceCase(label))
+		heading := fmt.Sprintf("# %s\n", capitalise(label))
 		os.WriteFile(filepath, []byte(heading), 0o644)
 	}
 }
 
-func maybeOpenEditor(editFile bool, filepath, editorCommand string) {
-	if !editFile {
-		return
-	}
-	cmd := exec.Command(editorCommand, filepath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
-	}
-	if err := cmd.Process.Release(); err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
-	}
-	os.Exit(0)
+// writeFileAtomic replaces the file at filepath with data by writing to a
+// temporary file in the same directory and renaming it over the original.
+func writeFileAtomic(filepath string, data []byte) error {
+	tmp, err := os.CreateTemp(path.Dir(filepath), "."+path.Base(filepath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath)
 }
 
 func render(file *os.File) error {
@@ -2,6 +2,7 @@
 package note
 
 import (
+	"context"
 	"testing"
 )
 
@@ -37,7 +38,7 @@ func TestNote_Note(t *testing.T) {
 				EditFile:    tt.fields.EditFile,
 				HidePreview: tt.fields.HidePreview,
 			}
-			if err := n.Note(); (err != nil) != tt.wantErr {
+			if err := n.Note(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Note.Note() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1,12 +1,13 @@
 package note
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+	"os"
 	"strings"
 	"time"
 
-	"golang.org/x/net/html"
+	"github.com/mattn/go-runewidth"
 )
 
 const (
@@ -22,87 +23,158 @@ const (
 
 type noteType interface {
 	label() string
-	toMarkdown(string) (string, error)
+	toMarkdown(ctx context.Context, content string) (string, error)
 }
 
 type bookmark struct {
+	url         string
+	title       string
 	description string
 	tags        []string
+	// timeout bounds how long fetching the page's metadata may take. Zero
+	// means no limit.
+	timeout time.Duration
+	// archiveTo is the notes file to save a snapshot of the page next to, or
+	// empty not to save one.
+	archiveTo string
+	// snapshot is where the snapshot of the page is, relative to the notes
+	// file, if there is one.
+	snapshot string
+	// width is the width text is wrapped at, zero meaning the default.
+	width int
+	// unfetched is set on bookmarks written without their page's metadata
+	// because it could not be fetched.
+	unfetched bool
 }
 
+// unfetchedMarker flags a bookmark whose page could not be fetched, so that
+// "note bookmark refresh" can find it later.
+const unfetchedMarker = "<!-- unfetched -->"
+
 func (bookmark) label() string {
 	return "Bookmarks"
 }
 
-func (b bookmark) toMarkdown(content string) (string, error) {
-	title := fetchWebpageTitle(content)
+func (b bookmark) toMarkdown(ctx context.Context, content string) (string, error) {
+	content = normalizeURL(content)
+	var meta pageMeta
+	if isWebURL(content) {
+		var err error
+		if b.archiveTo != "" {
+			meta, b.snapshot, err = b.fetchSnapshot(ctx, content)
+		} else {
+			meta, err = b.fetch(ctx, content)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not fetch %s, bookmarking it as is: %v\n", content, err)
+			b.unfetched = true
+		}
+	}
+	return fmt.Sprint("\n", b.format(content, meta)), nil
+}
+
+// fetch downloads the metadata of the page at pageURL within b's timeout.
+func (b bookmark) fetch(ctx context.Context, pageURL string) (pageMeta, error) {
+	ctx, cancel := b.fetchContext(ctx)
+	defer cancel()
+	return fetchPageMeta(ctx, pageURL)
+}
+
+// fetchSnapshot downloads the page at pageURL within b's timeout and saves a
+// snapshot of it next to b's notes file. It returns the page's metadata and
+// where the snapshot is relative to the notes file.
+func (b bookmark) fetchSnapshot(ctx context.Context, pageURL string) (pageMeta, string, error) {
+	ctx, cancel := b.fetchContext(ctx)
+	defer cancel()
+	return saveSnapshot(ctx, b.archiveTo, pageURL, columns(b.width))
+}
+
+func (b bookmark) fetchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.timeout > 0 {
+		return context.WithTimeout(ctx, b.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// format renders b as a bookmark of pageURL titled and described by meta,
+// unless b has a description of its own.
+func (b bookmark) format(pageURL string, meta pageMeta) string {
+	title := meta.title
 	if title == "" {
-		title = content
+		title = pageURL
 	}
+	if meta.siteName != "" && !strings.Contains(title, meta.siteName) {
+		title = fmt.Sprint(title, " | ", meta.siteName)
+	}
+	// The link is the URL the page was bookmarked by rather than its canonical
+	// URL, so that bookmarking it again by the same URL finds it.
+	link := normalizeURL(pageURL)
 	title = strings.TrimSpace(title)
-	if len(title)+len(content)+4 > 80 {
-		title = wordWrap(title, wrapWidth)
+	width := columns(b.width)
+	if width > 0 && runewidth.StringWidth(title)+runewidth.StringWidth(link)+4 > width {
+		// The title is wrapped short of the "[" it is opened with.
+		title = wordWrap(title, max(width-1, 1))
+	}
+	description := b.description
+	if description == "" {
+		description = wordWrap(meta.description, width)
+	}
+	if b.snapshot != "" {
+		description = strings.TrimLeft(
+			fmt.Sprintf("%s\n[%s](%s)", description, snapshotLabel, b.snapshot),
+			"\n",
+		)
+	}
+	if b.unfetched {
+		description = strings.TrimLeft(description+"\n"+unfetchedMarker, "\n")
 	}
 	tags := make([]string, len(b.tags))
 	for i, tag := range b.tags {
 		tags[i] = fmt.Sprint("**#", tag, "**")
 	}
-	tagsLine := "tags:"
+	tagsLine := "tags:\n"
 	if len(tags) > 0 {
 		tagsLine = fmt.Sprintf("tags: %s  \n", strings.Join(tags, " "))
 	}
 	return fmt.Sprintf(
-		"\n[%s](%s)\\\n%s%s\n",
+		"[%s](%s)\\\n%s%s\n",
 		strings.TrimSpace(title),
-		content,
+		link,
 		tagsLine,
-		b.description,
-	), nil
+		description,
+	)
 }
 
-func fetchWebpageTitle(url string) string {
-	resp, err := http.Get(url)
-	if err != nil {
-		return ""
-	}
-	defer resp.Body.Close()
-	doc, err := html.Parse(resp.Body)
-	if err != nil {
-		return ""
-	}
-	var traverse func(*html.Node) (string, bool)
-	traverse = func(n *html.Node) (string, bool) {
-		if n.Type == html.ElementNode && n.Data == "title" {
-			return n.FirstChild.Data, true
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			if result, ok := traverse(c); ok {
-				return result, ok
-			}
-		}
-		return "", false
-	}
-	title, _ := traverse(doc)
-	return title
+type notes struct {
+	// verbatim keeps content spanning several lines as it is.
+	verbatim bool
+	// width is the width text is wrapped at, zero meaning the default.
+	width int
 }
 
-type notes struct{}
-
 func (notes) label() string {
 	return "Notes"
 }
 
-func (notes) toMarkdown(content string) (string, error) {
-	note := fmt.Sprintln(wordWrap(sentenceCase(content), wrapWidth))
+func (n notes) toMarkdown(_ context.Context, content string) (string, error) {
+	if n.verbatim && strings.Contains(content, "\n") {
+		return fencedBlock(content, ""), nil
+	}
+	note := fmt.Sprintln(strings.TrimRight(formatProse(content, columns(n.width)), "\n"))
 	return note, nil
 }
 
 type issue struct {
 	createdAt   time.Time
+	id          int
 	title       string
 	description string
 	status      Status
 	tags        []string
+	// verbatim keeps a description spanning several lines as it is.
+	verbatim bool
+	// width is the width text is wrapped at, zero meaning the default.
+	width int
 }
 
 // Status  
@@ -143,29 +215,71 @@ func (issue) label() string {
 	return "Issues"
 }
 
-func (i issue) toMarkdown(content string) (string, error) {
+func (i issue) toMarkdown(_ context.Context, content string) (string, error) {
 	sb := &strings.Builder{}
-	fmt.Fprintln(sb, "\n##", wordWrap(sentenceCase(i.title), wrapWidth))
+	heading := capitalise(i.title)
+	if i.id > 0 {
+		heading = fmt.Sprint("#", i.id, " ", heading)
+	}
+	fmt.Fprintln(sb, "\n##", heading)
 	fmt.Fprintln(sb, "\ncreatedAt:", i.CreatedAtFormatted())
 	fmt.Fprintln(sb, "status:", i.status)
 	fmt.Fprintln(sb, "labels:", strings.Join(i.tags, ", "))
 	sb.WriteString("\n")
-	fmt.Fprint(sb, wordWrap(content, wrapWidth))
+	if i.verbatim && strings.Contains(content, "\n") {
+		sb.WriteString(strings.TrimSuffix(fencedBlock(content, ""), "\n"))
+	} else {
+		fmt.Fprint(sb, strings.TrimRight(formatProse(content, columns(i.width)), "\n"))
+	}
 	sb.WriteString("\n\n")
-	sb.WriteString("### Comments")
+	sb.WriteString(commentsHeading)
 	sb.WriteString("\n")
 	sb.WriteString("\n")
 	sb.WriteString("---")
 	return sb.String(), nil
 }
 
-type todo struct{}
+type todo struct {
+	doneAt   time.Time
+	due      time.Time
+	from     time.Time
+	text     string
+	priority Priority
+	done     bool
+	// verbatim keeps the lines after the first as they are, in a fenced
+	// block under the todo.
+	verbatim bool
+	// width is the width text is wrapped at, zero meaning the default.
+	width int
+	// day is the day the todo is written under, which inline due dates are
+	// worked out from, zero meaning now.
+	day time.Time
+}
 
 func (todo) label() string {
 	return "Todo"
 }
 
-func (todo) toMarkdown(content string) (string, error) {
-	note := fmt.Sprintln(wordWrap(fmt.Sprint("- [ ] ", sentenceCase(content)), wrapWidth))
+func (t todo) toMarkdown(_ context.Context, content string) (string, error) {
+	var details string
+	if t.verbatim {
+		content, details, _ = strings.Cut(content, "\n")
+		details = strings.Trim(details, "\n")
+	}
+	day := t.day
+	if day.IsZero() {
+		day = time.Now()
+	}
+	content = resolvePriorities(resolveDueDates(content, day))
+	if t.priority != NoPriority {
+		content = fmt.Sprint(content, " !", t.priority)
+	}
+	if !t.due.IsZero() {
+		content = fmt.Sprint(content, " @", t.due.Format(time.DateOnly))
+	}
+	note := fmt.Sprintln(strings.TrimRight(formatProse("- [ ] "+content, columns(t.width)), "\n"))
+	if details != "" {
+		note += fencedBlock(details, "  ")
+	}
 	return note, nil
 }
@@ -1,6 +1,7 @@
 package note
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -40,12 +41,60 @@ func TestNoteTypeToMarkdown(t *testing.T) {
 			content:  "This is a test issue.",
 			expected: "## Test Issue\n\ncreatedAt: Sat Jan  1 00:00:00 UTC 2022\nstatus: Open\nlabels: bug, enhancement\n\nThis is a test issue.\n\n### Comments\n\n---",
 		},
+		{
+			name: "IssueWithID",
+			noteType: &issue{
+				id:        14,
+				title:     "login fails on retry",
+				status:    Open,
+				tags:      []string{"bug"},
+				createdAt: time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+			},
+			content:  "Second attempt hangs.",
+			expected: "## #14 Login fails on retry\n\ncreatedAt: Sat Jan  1 00:00:00 UTC 2022\nstatus: Open\nlabels: bug\n\nSecond attempt hangs.\n\n### Comments\n\n---",
+		},
 		{
 			name:     "TodoCreation",
 			noteType: new(todo),
 			content:  "This is a test todo.",
 			expected: "- [ ] This is a test todo.",
 		},
+		{
+			name:     "TodoWithDueDate",
+			noteType: todo{due: time.Date(2026, time.November, 3, 0, 0, 0, 0, time.Local)},
+			content:  "send the report",
+			expected: "- [ ] Send the report @2026-11-03",
+		},
+		{
+			name:     "TodoWithPriority",
+			noteType: todo{priority: HighPriority},
+			content:  "fix the build",
+			expected: "- [ ] Fix the build !high",
+		},
+		{
+			name:     "VerbatimNotes",
+			noteType: notes{verbatim: true},
+			content:  "--- FAIL: TestParse\n    got `a`. expected `b`",
+			expected: "```\n--- FAIL: TestParse\n    got `a`. expected `b`\n```",
+		},
+		{
+			name:     "VerbatimSingleLine",
+			noteType: notes{verbatim: true},
+			content:  "one line piped in",
+			expected: "One line piped in",
+		},
+		{
+			name:     "VerbatimTodo",
+			noteType: todo{verbatim: true},
+			content:  "fix the flaky test\n\n--- FAIL: TestFetch\n```go\nfetch()\n```",
+			expected: "- [ ] Fix the flaky test\n  ````\n  --- FAIL: TestFetch\n  ```go\n  fetch()\n  ```\n  ````",
+		},
+		{
+			name:     "VerbatimIssue",
+			noteType: &issue{title: "build fails", status: Open, verbatim: true},
+			content:  "make: *** [test] Error 1\nexit status 2",
+			expected: "## Build fails\n\ncreatedAt: \nstatus: Open\nlabels: \n\n```\nmake: *** [test] Error 1\nexit status 2\n```\n\n### Comments\n\n---",
+		},
 		// Edge Cases
 		{
 			name:     "EmptyContent",
@@ -74,7 +123,7 @@ func TestNoteTypeToMarkdown(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			md, err := tc.noteType.toMarkdown(tc.content)
+			md, err := tc.noteType.toMarkdown(context.Background(), tc.content)
 			if err != nil {
 				t.Errorf("Error converting %s to Markdown: %v", tc.name, err)
 			}
@@ -0,0 +1,391 @@
+package note
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// span is the half-open byte range [start, end) an entry occupies in the file
+// it was parsed from.
+type span struct {
+	start int
+	end   int
+}
+
+// line is a single line of a file without its trailing newline. Its span
+// includes the newline.
+type line struct {
+	text string
+	span
+}
+
+// dateSection is a "## Mon, 02 Jan 2006" heading, as written by newHeading,
+// along with everything up to the next heading.
+type dateSection struct {
+	date    time.Time
+	heading string
+	span
+}
+
+type parsedIssue struct {
+	issue
+	comments []string
+	span
+}
+
+type parsedTodo struct {
+	section dateSection
+	todo
+	span
+	// indent is the number of columns the item is indented by, which is
+	// more than its parent's for subtasks.
+	indent int
+	// parent is the index of the todo this one is a subtask of, or -1.
+	parent int
+	// textEnd is where the todo's text ends, before the newline and any
+	// fenced block under it. Annotations are written there.
+	textEnd int
+}
+
+type parsedBookmark struct {
+	section dateSection
+	bookmark
+	span
+}
+
+const commentsHeading = "### Comments"
+
+var (
+	todoItem     = regexp.MustCompile(`^([ \t]*)- \[([ xX])\] ?(.*)$`)
+	fenceOpening = regexp.MustCompile("^(```+|~~~+)")
+	bookmarkLink = regexp.MustCompile(`\]\(([^\s()]*)\)\\(?:\n|$)`)
+	bookmarkTag  = regexp.MustCompile(`\*\*#([^*]*)\*\*`)
+)
+
+func splitLines(data []byte) []line {
+	var lines []line
+	for offset := 0; offset < len(data); {
+		end := len(data)
+		if next := bytes.IndexByte(data[offset:], '\n'); next >= 0 {
+			end = offset + next + 1
+		}
+		text := strings.TrimSuffix(string(data[offset:end]), "\n")
+		lines = append(lines, line{text: text, span: span{offset, end}})
+		offset = end
+	}
+	return lines
+}
+
+// skipBlank returns the index of the first non-blank line at or after i.
+func skipBlank(lines []line, i int) int {
+	for i < len(lines) && isBlank(lines[i].text) {
+		i++
+	}
+	return i
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+func isHeading(s string) bool {
+	return strings.HasPrefix(s, "#")
+}
+
+// parseSections returns the date sections in lines, in file order. Headings
+// in fenced blocks are part of the section they are in.
+func parseSections(lines []line) []dateSection {
+	var sections []dateSection
+	// fence is the fence of the block the line is in, if it is in one.
+	var fence string
+	for _, l := range lines {
+		trimmed := strings.TrimLeft(l.text, " \t")
+		if fence != "" {
+			if closesFence(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence = fenceOpening.FindString(trimmed); fence != "" || !strings.HasPrefix(l.text, "## ") {
+			continue
+		}
+		if n := len(sections); n > 0 {
+			sections[n-1].end = l.start
+		}
+		heading := strings.TrimPrefix(l.text, "## ")
+		date, _ := time.ParseInLocation(headingDateFormat, heading, time.Local)
+		sections = append(sections, dateSection{date: date, heading: heading, span: l.span})
+	}
+	if n := len(sections); n > 0 {
+		sections[n-1].end = lines[len(lines)-1].end
+	}
+	return sections
+}
+
+// sectionAt returns the date section containing offset, or the zero section if
+// offset comes before the first heading.
+func sectionAt(sections []dateSection, offset int) dateSection {
+	for i := len(sections) - 1; i >= 0; i-- {
+		if sections[i].start <= offset {
+			return sections[i]
+		}
+	}
+	return dateSection{}
+}
+
+// parseTodos returns the todo items in data. An item is a "- [ ]" or "- [x]"
+// line along with any wrapped lines that follow it. Items indented under
+// another item in the same section are its subtasks.
+func parseTodos(data []byte) []parsedTodo {
+	lines := splitLines(data)
+	sections := parseSections(lines)
+	var todos []parsedTodo
+	var ancestors []int
+	// inFence is set while in a fenced block under the last todo, which is
+	// part of the todo without being part of its text.
+	inFence := false
+	for _, l := range lines {
+		n := len(todos)
+		trimmed := strings.TrimLeft(l.text, " \t")
+		fence := strings.HasPrefix(trimmed, "```")
+		if n > 0 && todos[n-1].end == l.start && (inFence || fence && trimmed != l.text) {
+			inFence = inFence != fence
+			todos[n-1].end = l.end
+			continue
+		}
+		if m := todoItem.FindStringSubmatch(l.text); m != nil {
+			t := parsedTodo{
+				section: sectionAt(sections, l.start),
+				todo:    todo{text: m[3], done: m[2] != " "},
+				span:    l.span,
+				indent:  len(strings.ReplaceAll(m[1], "\t", "    ")),
+				parent:  -1,
+				textEnd: l.start + len(l.text),
+			}
+			for len(ancestors) > 0 {
+				a := todos[ancestors[len(ancestors)-1]]
+				if a.indent < t.indent && a.section.start == t.section.start {
+					t.parent = ancestors[len(ancestors)-1]
+					break
+				}
+				ancestors = ancestors[:len(ancestors)-1]
+			}
+			ancestors = append(ancestors, len(todos))
+			todos = append(todos, t)
+			continue
+		}
+		if n == 0 || todos[n-1].end != l.start || isBlank(l.text) || isHeading(l.text) ||
+			strings.HasPrefix(strings.TrimSpace(l.text), "- ") {
+			continue
+		}
+		todos[n-1].text += " " + strings.TrimSpace(l.text)
+		todos[n-1].end = l.end
+		todos[n-1].textEnd = l.start + len(l.text)
+	}
+	for i := range todos {
+		todos[i].parseAnnotations()
+	}
+	return todos
+}
+
+// subtreeEnd returns where the i-th todo's subtasks, and theirs, end.
+func subtreeEnd(todos []parsedTodo, i int) int {
+	end := todos[i].end
+	for j := i + 1; j < len(todos) && isDescendant(todos, j, i); j++ {
+		end = todos[j].end
+	}
+	return end
+}
+
+// isDescendant reports whether the j-th todo is a subtask of the i-th one,
+// directly or not.
+func isDescendant(todos []parsedTodo, j, i int) bool {
+	for p := todos[j].parent; p >= 0; p = todos[p].parent {
+		if p == i {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBookmarks returns the bookmarks in data. Each bookmark is a paragraph
+// starting with a "[title](url)\" line. Paragraphs that follow a bookmark in
+// the same section are treated as part of its description.
+func parseBookmarks(data []byte) []parsedBookmark {
+	lines := splitLines(data)
+	sections := parseSections(lines)
+	var bookmarks []parsedBookmark
+	for i := 0; i < len(lines); {
+		if isBlank(lines[i].text) || isHeading(lines[i].text) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(lines) && !isBlank(lines[j].text) && !isHeading(lines[j].text) {
+			j++
+		}
+		text := string(data[lines[i].start:lines[j-1].end])
+		section := sectionAt(sections, lines[i].start)
+		if b, ok := parseBookmark(text); ok {
+			bookmarks = append(bookmarks, parsedBookmark{
+				section:  section,
+				bookmark: b,
+				span:     span{lines[i].start, lines[j-1].end},
+			})
+		} else if n := len(bookmarks); n > 0 && bookmarks[n-1].section.start == section.start {
+			b := &bookmarks[n-1]
+			b.description = strings.TrimSpace(b.description + "\n\n" + text)
+			b.end = lines[j-1].end
+		}
+		i = j
+	}
+	for i := range bookmarks {
+		b := &bookmarks[i]
+		if description, ok := strings.CutSuffix(b.description, unfetchedMarker); ok {
+			b.description = strings.TrimSpace(description)
+			b.unfetched = true
+		}
+		if m := snapshotLink.FindStringSubmatchIndex(b.description); m != nil {
+			b.snapshot = b.description[m[2]:m[3]]
+			b.description = strings.TrimSpace(b.description[:m[0]])
+		}
+	}
+	return bookmarks
+}
+
+func parseBookmark(text string) (bookmark, bool) {
+	if !strings.HasPrefix(text, "[") {
+		return bookmark{}, false
+	}
+	m := bookmarkLink.FindStringSubmatchIndex(text)
+	if m == nil {
+		return bookmark{}, false
+	}
+	b := bookmark{
+		title: strings.ReplaceAll(text[1:m[0]], "\n", " "),
+		url:   text[m[2]:m[3]],
+	}
+	rest := text[m[1]:]
+	if strings.HasPrefix(rest, "tags:") {
+		tagsLine, description, _ := strings.Cut(rest, "\n")
+		for _, tag := range bookmarkTag.FindAllStringSubmatch(tagsLine, -1) {
+			b.tags = append(b.tags, tag[1])
+		}
+		rest = description
+	}
+	b.description = strings.TrimSpace(rest)
+	return b, true
+}
+
+// parseIssues returns the issues in data. An issue runs from its "## "
+// heading up to the next one.
+func parseIssues(data []byte) []parsedIssue {
+	lines := splitLines(data)
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.text
+	}
+	headings := issueHeadings(texts)
+	issues := make([]parsedIssue, 0, len(headings))
+	for n, h := range headings {
+		end := len(lines)
+		if n+1 < len(headings) {
+			end = headings[n+1]
+		}
+		issues = append(issues, parseIssue(lines[h:end]))
+	}
+	return issues
+}
+
+// issueHeadings returns the indices of the lines that start issues: the "## "
+// headings that come before any other issue or right after the "---" that
+// ends one. Headings in fenced blocks, or anywhere else in a description, are
+// part of the description.
+func issueHeadings(lines []string) []int {
+	var headings []int
+	// fence is the fence of the block the line is in, if it is in one.
+	var fence string
+	boundary := true
+	for i, l := range lines {
+		l = strings.TrimSuffix(l, "\n")
+		trimmed := strings.TrimLeft(l, " \t")
+		switch {
+		case fence != "":
+			if closesFence(trimmed, fence) {
+				fence = ""
+			}
+		case fenceOpening.MatchString(trimmed):
+			fence = fenceOpening.FindString(trimmed)
+			boundary = false
+		case isBlank(l):
+		case strings.HasPrefix(l, "---"):
+			boundary = true
+		default:
+			if _, _, ok := parseIssueHeading(l); ok && (boundary || len(headings) == 0) {
+				headings = append(headings, i)
+			}
+			boundary = false
+		}
+	}
+	return headings
+}
+
+// closesFence reports whether line, without its indent, closes a fenced block
+// opened with fence.
+func closesFence(line, fence string) bool {
+	return strings.HasPrefix(line, fence) &&
+		strings.TrimSpace(strings.TrimLeft(line, fence[:1])) == ""
+}
+
+func parseIssue(lines []line) parsedIssue {
+	var p parsedIssue
+	p.start, p.end = lines[0].start, lines[len(lines)-1].end
+	p.id, p.title, _ = parseIssueHeading(lines[0].text)
+	i := 1
+	for ; i < len(lines) && !isBlank(lines[i].text); i++ {
+		p.title += " " + strings.TrimSpace(lines[i].text)
+	}
+	i = skipBlank(lines, i)
+	for i < len(lines) && parseIssueField(&p, lines[i].text) {
+		i++
+	}
+	var body []string
+	for ; i < len(lines) && lines[i].text != commentsHeading; i++ {
+		body = append(body, lines[i].text)
+	}
+	p.description = strings.TrimSpace(strings.Join(body, "\n"))
+	for i++; i < len(lines) && !strings.HasPrefix(lines[i].text, "---"); i++ {
+		text := lines[i].text
+		switch {
+		case strings.HasPrefix(text, "- "):
+			p.comments = append(p.comments, strings.TrimPrefix(text, "- "))
+		case !isBlank(text) && len(p.comments) > 0:
+			p.comments[len(p.comments)-1] += " " + strings.TrimSpace(text)
+		}
+	}
+	return p
+}
+
+// parseIssueField reads a "key: value" metadata line into p, reporting whether
+// text was a known field.
+func parseIssueField(p *parsedIssue, text string) bool {
+	key, value, _ := strings.Cut(text, ":")
+	value = strings.TrimSpace(value)
+	switch key {
+	case "createdAt":
+		p.createdAt, _ = time.Parse(time.UnixDate, value)
+	case "status":
+		p.status = Status(value)
+	case "labels":
+		for _, label := range strings.Split(value, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				p.tags = append(p.tags, label)
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
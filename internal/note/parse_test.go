@@ -0,0 +1,234 @@
+package note
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIssuesRoundTrip(t *testing.T) {
+	createdAt := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	issues := []struct {
+		issue   *issue
+		content string
+	}{
+		{
+			issue: &issue{
+				id:        1,
+				title:     "login fails on retry",
+				status:    Open,
+				tags:      []string{"bug", "urgent"},
+				createdAt: createdAt,
+			},
+			content: "The login feature fails when the password is retried.",
+		},
+		{
+			issue: &issue{
+				id:        2,
+				title:     "dark mode",
+				status:    InProgress,
+				createdAt: createdAt.Add(24 * time.Hour),
+			},
+			content: strings.Repeat("A long description that wraps. ", 6),
+		},
+		{
+			issue:   &issue{id: 3, status: Closed},
+			content: "",
+		},
+	}
+	file := "# Issues\n"
+	for _, i := range issues {
+		md, err := i.issue.toMarkdown(context.Background(), i.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file += md
+	}
+	parsed := parseIssues([]byte(file))
+	if len(parsed) != len(issues) {
+		t.Fatalf("parseIssues() returned %d issues, expected %d", len(parsed), len(issues))
+	}
+	for n, p := range parsed {
+		want := issues[n].issue
+		if p.id != want.id {
+			t.Errorf("issue %d: id = %d, expected %d", n, p.id, want.id)
+		}
+		if p.title != capitalise(want.title) {
+			t.Errorf("issue %d: title = %q, expected %q", n, p.title, capitalise(want.title))
+		}
+		if p.status != want.status {
+			t.Errorf("issue %d: status = %q, expected %q", n, p.status, want.status)
+		}
+		if !slices.Equal(p.tags, want.tags) {
+			t.Errorf("issue %d: tags = %q, expected %q", n, p.tags, want.tags)
+		}
+		if !p.createdAt.Equal(want.createdAt) {
+			t.Errorf("issue %d: createdAt = %v, expected %v", n, p.createdAt, want.createdAt)
+		}
+		description := formatProse(issues[n].content, wrapWidth)
+		if p.description != strings.TrimSpace(description) {
+			t.Errorf("issue %d: description = %q, expected %q", n, p.description, description)
+		}
+		if section := file[p.start:p.end]; !strings.HasPrefix(section, fmt.Sprint("## #", want.id)) {
+			t.Errorf("issue %d: span starts with %q", n, section)
+		}
+	}
+	if parsed[len(parsed)-1].end != len(file) {
+		t.Errorf("last issue ends at %d, expected %d", parsed[len(parsed)-1].end, len(file))
+	}
+}
+
+func TestParseIssueComments(t *testing.T) {
+	file := "# Issues\n\n## #1 Title\n\nstatus: Open\n\n### Comments\n\n" +
+		"- Mon Jan  3 10:30:00 UTC 2022: first\n" +
+		"- Mon Jan  3 10:31:00 UTC 2022: second which\n  wraps\n\n---"
+	parsed := parseIssues([]byte(file))
+	if len(parsed) != 1 {
+		t.Fatalf("parseIssues() returned %d issues, expected 1", len(parsed))
+	}
+	expected := []string{
+		"Mon Jan  3 10:30:00 UTC 2022: first",
+		"Mon Jan  3 10:31:00 UTC 2022: second which wraps",
+	}
+	if !slices.Equal(parsed[0].comments, expected) {
+		t.Errorf("comments = %q, expected %q", parsed[0].comments, expected)
+	}
+}
+
+func TestParseIssuesSkipsDescriptionHeadings(t *testing.T) {
+	description := "```md\n## v1.0\n```\n\n## Steps"
+	file := "# Issues\n\n## #1 First\n\nstatus: Open\n\n" + description +
+		"\n\n### Comments\n\n- Mon Jan  3 10:30:00 UTC 2022: a comment\n\n---\n" +
+		"## #2 Second\n\nstatus: Closed\n\n### Comments\n\n---"
+	parsed := parseIssues([]byte(file))
+	if len(parsed) != 2 {
+		t.Fatalf("parseIssues() returned %d issues, expected 2", len(parsed))
+	}
+	if parsed[0].title != "First" || parsed[1].title != "Second" || parsed[1].status != Closed {
+		t.Errorf("parseIssues() = %+v", parsed)
+	}
+	if !strings.Contains(parsed[0].description, description) {
+		t.Errorf("description = %q, expected it to contain %q", parsed[0].description, description)
+	}
+	if len(parsed[0].comments) != 1 {
+		t.Errorf("comments = %q, expected one comment", parsed[0].comments)
+	}
+}
+
+func TestParseTodosRoundTrip(t *testing.T) {
+	contents := []string{
+		"finish writing documentation",
+		strings.Repeat("a todo long enough to be wrapped onto the next line ", 2),
+		"review PRs",
+	}
+	today := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.Local)
+	file := "# Todo\n\n## " + today.AddDate(0, 0, -1).Format(headingDateFormat) + "\n\n"
+	for n, content := range contents {
+		if n == 2 {
+			file += "\n## " + today.Format(headingDateFormat) + "\n\n"
+		}
+		md, err := todo{}.toMarkdown(context.Background(), content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file += md
+	}
+	file = strings.Replace(file, "- [ ] Review PRs", "- [x] Review PRs", 1)
+	parsed := parseTodos([]byte(file))
+	if len(parsed) != len(contents) {
+		t.Fatalf("parseTodos() returned %d todos, expected %d", len(parsed), len(contents))
+	}
+	for n, p := range parsed {
+		expected := strings.TrimSpace(capitalise(contents[n]))
+		if p.text != expected {
+			t.Errorf("todo %d: text = %q, expected %q", n, p.text, expected)
+		}
+		if p.done != (n == 2) {
+			t.Errorf("todo %d: done = %v", n, p.done)
+		}
+		date := today
+		if n < 2 {
+			date = today.AddDate(0, 0, -1)
+		}
+		if !p.section.date.Equal(date) {
+			t.Errorf("todo %d: section date = %v, expected %v", n, p.section.date, date)
+		}
+		item := file[p.start:p.end]
+		if !strings.HasPrefix(item, "- [") || !strings.HasSuffix(item, "\n") {
+			t.Errorf("todo %d: span covers %q", n, item)
+		}
+	}
+}
+
+func TestParseTodoWithFencedBlock(t *testing.T) {
+	file := "- [ ] Fix the flaky test\n" +
+		"  ```\n" +
+		"  --- FAIL: TestFetch\n" +
+		"\n" +
+		"  - [ ] not a todo\n" +
+		"  ```\n" +
+		"- [ ] Next\n"
+	parsed := parseTodos([]byte(file))
+	if len(parsed) != 2 {
+		t.Fatalf("parseTodos() returned %d todos, expected 2: %+v", len(parsed), parsed)
+	}
+	if parsed[0].text != "Fix the flaky test" {
+		t.Errorf("text = %q, expected the fenced block to be left out", parsed[0].text)
+	}
+	if item := file[parsed[0].start:parsed[0].end]; !strings.HasSuffix(item, "  ```\n") {
+		t.Errorf("span covers %q, expected it to include the fenced block", item)
+	}
+}
+
+func TestParseBookmarksRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><head><title>Example Domain</title></head></html>")
+	}))
+	defer server.Close()
+	bookmarks := []bookmark{
+		{
+			url:         server.URL,
+			title:       "Example Domain",
+			tags:        []string{"ai", "research"},
+			description: "OpenAI",
+		},
+		{url: server.URL + "/no-tags", title: "Example Domain"},
+		{url: "invalid-url", title: "invalid-url", description: "Just a link"},
+	}
+	file := "# Bookmarks\n\n## " + time.Now().Format(headingDateFormat) + "\n"
+	for _, b := range bookmarks {
+		md, err := b.toMarkdown(context.Background(), b.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file += md
+	}
+	parsed := parseBookmarks([]byte(file))
+	if len(parsed) != len(bookmarks) {
+		t.Fatalf(
+			"parseBookmarks() returned %d bookmarks, expected %d",
+			len(parsed),
+			len(bookmarks),
+		)
+	}
+	for n, p := range parsed {
+		want := bookmarks[n]
+		if p.url != want.url || p.title != want.title || p.description != want.description {
+			t.Errorf("bookmark %d: got %+v, expected %+v", n, p.bookmark, want)
+		}
+		if !slices.Equal(p.tags, want.tags) {
+			t.Errorf("bookmark %d: tags = %q, expected %q", n, p.tags, want.tags)
+		}
+		if p.section.heading == "" {
+			t.Errorf("bookmark %d: missing date heading", n)
+		}
+		if !strings.HasPrefix(file[p.start:p.end], "["+want.title) {
+			t.Errorf("bookmark %d: span covers %q", n, file[p.start:p.end])
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package note
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Priority ranks how urgent a todo is. The zero value means the todo has no
+// priority, which ranks below every other.
+type Priority int
+
+const (
+	// NoPriority is the priority of todos that were not given one.
+	NoPriority Priority = iota
+	// LowPriority is written as "!low".
+	LowPriority
+	// MediumPriority is written as "!med".
+	MediumPriority
+	// HighPriority is written as "!high".
+	HighPriority
+)
+
+// priorityToken matches a "!high" style priority written inline in a todo.
+var priorityToken = regexp.MustCompile(`(^|\s)!(\w+)\b`)
+
+// ParsePriority converts a priority such as "high", "med" or "l" into a
+// Priority.
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, "!")) {
+	case "high", "hi", "h":
+		return HighPriority, nil
+	case "medium", "med", "m":
+		return MediumPriority, nil
+	case "low", "lo", "l":
+		return LowPriority, nil
+	}
+	return NoPriority, fmt.Errorf("unknown priority %q, expected high, med or low", s)
+}
+
+func (p Priority) String() string {
+	switch p {
+	case HighPriority:
+		return "high"
+	case MediumPriority:
+		return "med"
+	case LowPriority:
+		return "low"
+	}
+	return ""
+}
+
+// resolvePriorities rewrites the inline priorities in content to the "!high",
+// "!med" or "!low" form they are stored in. Tokens that are not priorities are
+// left alone.
+func resolvePriorities(content string) string {
+	return priorityToken.ReplaceAllStringFunc(content, func(token string) string {
+		m := priorityToken.FindStringSubmatch(token)
+		p, err := ParsePriority(m[2])
+		if err != nil {
+			return token
+		}
+		return m[1] + "!" + p.String()
+	})
+}
@@ -0,0 +1,21 @@
+package note
+
+import "testing"
+
+func TestResolvePriorities(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "fix the build !high", expected: "fix the build !high"},
+		{input: "!H fix the build", expected: "!high fix the build"},
+		{input: "plan the offsite !medium", expected: "plan the offsite !med"},
+		{input: "tidy up !lo", expected: "tidy up !low"},
+		{input: "wow! that's !important", expected: "wow! that's !important"},
+	}
+	for _, tc := range tests {
+		if got := resolvePriorities(tc.input); got != tc.expected {
+			t.Errorf("resolvePriorities(%q) = %q, expected %q", tc.input, got, tc.expected)
+		}
+	}
+}
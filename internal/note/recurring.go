@@ -0,0 +1,256 @@
+package note
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurringTodo is a todo that is added to the todos file every time its
+// rule comes around. Rules are stored per project, next to the todos file.
+type recurringTodo struct {
+	// Checked is the last day, as "2006-01-02", that occurrences have been
+	// added up to.
+	Checked string `json:"checked"`
+	Every   string `json:"every"`
+	Todo    string `json:"todo"`
+}
+
+// recurrence is how often a recurring todo repeats: every day, on a weekday or
+// on a day of the month.
+type recurrence struct {
+	weekday  time.Weekday
+	monthDay int
+	daily    bool
+}
+
+func parseRecurrence(s string) (recurrence, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "day", "daily":
+		return recurrence{daily: true}, nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return recurrence{weekday: day}, nil
+		}
+	}
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil &&
+			strings.HasSuffix(s, suffix) && n >= 1 && n <= 31 {
+			return recurrence{monthDay: n}, nil
+		}
+	}
+	return recurrence{}, fmt.Errorf(
+		"could not understand %q, expected day, a weekday or a day of the month such as 1st",
+		s,
+	)
+}
+
+// on reports whether day is an occurrence of r. Days of the month that a
+// month does not have fall on its last day instead.
+func (r recurrence) on(day time.Time) bool {
+	switch {
+	case r.daily:
+		return true
+	case r.monthDay > 0:
+		lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		return day.Day() == min(r.monthDay, lastDay)
+	default:
+		return day.Weekday() == r.weekday
+	}
+}
+
+// latest returns the most recent occurrence of r after since, up to and
+// including today.
+func (r recurrence) latest(since, today time.Time) (time.Time, bool) {
+	// Every rule comes around at least once a month.
+	for i := 0; i <= 31; i++ {
+		day := today.AddDate(0, 0, -i)
+		if !day.After(since) {
+			break
+		}
+		if r.on(day) {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// next returns the first occurrence of r on or after today.
+func (r recurrence) next(today time.Time) time.Time {
+	day := today
+	for !r.on(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// AddRecurringTodo saves a rule that adds content as a todo every time every
+// comes around, starting today, and returns the day it will next be added on.
+// Todos are wrapped at width.
+func AddRecurringTodo(notesPath, every, content string, now time.Time, width int) (time.Time, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return time.Time{}, errors.New("nothing to note here")
+	}
+	r, err := parseRecurrence(every)
+	if err != nil {
+		return time.Time{}, err
+	}
+	today := startOfDay(now)
+	rules, err := loadRecurring(notesPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	rules = append(rules, recurringTodo{
+		Checked: today.AddDate(0, 0, -1).Format(time.DateOnly),
+		Every:   every,
+		Todo:    content,
+	})
+	if err = saveRecurring(notesPath, rules); err != nil {
+		return time.Time{}, err
+	}
+	if _, err = AddRecurringTodos(notesPath, now, width); err != nil {
+		return time.Time{}, err
+	}
+	return r.next(today), nil
+}
+
+// AddRecurringTodos adds a todo for every recurring rule that has come around
+// since it was last checked, under the heading of the day it came around on.
+// Only the latest occurrence of each rule is added, so a rule missed for a few
+// weeks yields a single todo. Todos are wrapped at width. It returns how many
+// todos were added.
+func AddRecurringTodos(notesPath string, now time.Time, width int) (int, error) {
+	rules, err := loadRecurring(notesPath)
+	if err != nil || len(rules) == 0 {
+		return 0, err
+	}
+	today := startOfDay(now)
+	checked := today.Format(time.DateOnly)
+	// The todos file is only made once a todo is added to it.
+	data, err := os.ReadFile(notesPath)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = []byte(fmt.Sprintf("# %s\n", todo{}.label())), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	added, changed := 0, false
+	for i, rule := range rules {
+		if rule.Checked == checked {
+			continue
+		}
+		r, err := parseRecurrence(rule.Every)
+		if err != nil {
+			return 0, err
+		}
+		since, err := time.ParseInLocation(time.DateOnly, rule.Checked, now.Location())
+		if err != nil {
+			return 0, fmt.Errorf("invalid recurring todo %q: %w", rule.Todo, err)
+		}
+		rules[i].Checked = checked
+		changed = true
+		day, ok := r.latest(since, today)
+		if !ok {
+			continue
+		}
+		item, err := todo{width: width, day: day}.toMarkdown(context.Background(), rule.Todo)
+		if err != nil {
+			return 0, err
+		}
+		if !hasTodo(data, day, item) {
+			data = addToDaySection(data, day, []byte(item))
+			added++
+		}
+	}
+	if added > 0 {
+		if err = os.MkdirAll(filepath.Dir(notesPath), 0o755); err != nil {
+			return 0, err
+		}
+		if err = writeFileAtomic(notesPath, data); err != nil {
+			return 0, err
+		}
+	}
+	if changed {
+		return added, saveRecurring(notesPath, rules)
+	}
+	return added, nil
+}
+
+func recurringPath(notesPath string) string {
+	return filepath.Join(filepath.Dir(notesPath), ".note", "recurring.json")
+}
+
+func loadRecurring(notesPath string) ([]recurringTodo, error) {
+	data, err := os.ReadFile(recurringPath(notesPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rules []recurringTodo
+	if err = json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid recurring todos file: %w", err)
+	}
+	return rules, nil
+}
+
+func saveRecurring(notesPath string, rules []recurringTodo) error {
+	path := recurringPath(notesPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// hasTodo reports whether the todo in item is already listed under day.
+func hasTodo(data []byte, day time.Time, item string) bool {
+	text := parseTodos([]byte(item))[0].text
+	return containsTodo(parseTodos(data), day.Format(headingDateFormat), text)
+}
+
+// containsTodo reports whether there is a todo with text under heading in todos.
+func containsTodo(todos []parsedTodo, heading, text string) bool {
+	for _, t := range todos {
+		if t.section.heading == heading && t.text == text {
+			return true
+		}
+	}
+	return false
+}
+
+// addToDaySection adds items to the end of the section for day, creating the
+// section in date order if there is none.
+func addToDaySection(data []byte, day time.Time, items []byte) []byte {
+	heading := day.Format(headingDateFormat)
+	for _, s := range parseSections(splitLines(data)) {
+		if s.heading == heading {
+			break
+		}
+		if !s.date.IsZero() && s.date.After(day) {
+			var out bytes.Buffer
+			out.Write(data[:s.start])
+			fmt.Fprintf(&out, "## %s\n\n", heading)
+			out.Write(items)
+			out.WriteString("\n")
+			out.Write(data[s.start:])
+			return out.Bytes()
+		}
+	}
+	return appendToSection(data, heading, items)
+}
@@ -0,0 +1,133 @@
+package note
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	friday := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.Local)
+	endOfMonth := time.Date(2026, time.September, 30, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		every   string
+		day     time.Time
+		on      bool
+		wantErr bool
+	}{
+		{every: "daily", day: friday, on: true},
+		{every: "Friday", day: friday, on: true},
+		{every: "mon", day: friday, on: false},
+		{every: "16th", day: friday, on: true},
+		{every: "1st", day: friday, on: false},
+		{every: "31st", day: endOfMonth, on: true},
+		{every: "31st", day: friday, on: false},
+		{every: "32nd", wantErr: true},
+		{every: "fortnight", wantErr: true},
+	}
+	for _, tc := range tests {
+		r, err := parseRecurrence(tc.every)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("parseRecurrence(%q) error = %v, wantErr %v", tc.every, err, tc.wantErr)
+		}
+		if !tc.wantErr && r.on(tc.day) != tc.on {
+			t.Errorf("parseRecurrence(%q).on(%s) = %v", tc.every, tc.day.Format(time.DateOnly), !tc.on)
+		}
+	}
+}
+
+func TestAddRecurringTodo(t *testing.T) {
+	now := time.Date(2026, time.October, 16, 9, 0, 0, 0, time.Local)
+	path := filepath.Join(t.TempDir(), "notes.todo.md")
+	next, err := AddRecurringTodo(path, "friday", "review PRs", now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.Equal(startOfDay(now)) {
+		t.Errorf("AddRecurringTodo() next = %s, expected today", next)
+	}
+	next, err = AddRecurringTodo(path, "mon", "plan the week", now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := startOfDay(now).AddDate(0, 0, 3); !next.Equal(expected) {
+		t.Errorf("AddRecurringTodo() next = %s, expected %s", next, expected)
+	}
+	for _, day := range []time.Time{now, now.Add(2 * time.Hour), now.AddDate(0, 0, 1)} {
+		if _, err = AddRecurringTodos(path, day, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Todo\n\n## Fri, 16 Oct 2026\n\n- [ ] Review PRs\n"
+	if string(got) != expected {
+		t.Errorf("AddRecurringTodos() wrote:\n%q\nexpected:\n%q", got, expected)
+	}
+}
+
+func TestAddRecurringTodosCatchesUp(t *testing.T) {
+	path := writeFixture(t, todosFixture)
+	rules := []recurringTodo{
+		{Checked: "2026-09-01", Every: "wednesday", Todo: "Water the plants @tomorrow"},
+		{Checked: "2026-10-16", Every: "sat", Todo: "Write release notes"},
+		{Checked: "2026-10-17", Every: "daily", Todo: "Stand-up"},
+	}
+	if err := saveRecurring(path, rules); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.October, 17, 8, 0, 0, 0, time.Local)
+	added, err := AddRecurringTodos(path, now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Errorf("AddRecurringTodos() added %d todos, expected 1", added)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Todo\n\n## Wed, 14 Oct 2026\n\n- [ ] Water the plants @2026-10-15\n\n" +
+		todosFixture[len("# Todo\n\n"):]
+	if string(got) != expected {
+		t.Errorf("AddRecurringTodos() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+	saved, err := loadRecurring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range saved {
+		if rule.Checked != "2026-10-17" {
+			t.Errorf("rule %q checked up to %s, expected 2026-10-17", rule.Todo, rule.Checked)
+		}
+	}
+}
+
+func TestAddRecurringTodosOnlyMakesFileWhenAdding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.todo.md")
+	rules := []recurringTodo{{Checked: "2026-10-16", Every: "monday", Todo: "Plan the week"}}
+	if err := saveRecurring(path, rules); err != nil {
+		t.Fatal(err)
+	}
+	friday := time.Date(2026, time.October, 17, 8, 0, 0, 0, time.Local)
+	if _, err := AddRecurringTodos(path, friday, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("AddRecurringTodos() made the todos file without adding a todo: %v", err)
+	}
+	if _, err := AddRecurringTodos(path, friday.AddDate(0, 0, 2), 14); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "# Todo\n\n## Mon, 19 Oct 2026\n\n- [ ] Plan the\n  week\n"; string(got) != expected {
+		t.Errorf("AddRecurringTodos() wrote:\n%q\nexpected:\n%q", got, expected)
+	}
+}
@@ -0,0 +1,363 @@
+package note
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// snapshotsDir is where page snapshots are kept, relative to the notes
+	// file. It is slash separated, as it is also used in markdown links.
+	snapshotsDir = ".note/archive"
+	// maxSnapshotSize caps how much of a page is read to snapshot it.
+	maxSnapshotSize = 8 << 20
+	snapshotLabel   = "offline copy"
+	// lineBreak stands for a <br> in inline content, as newlines in the page
+	// source are only whitespace. Being whitespace itself, it is dropped with
+	// the rest where line breaks are not kept.
+	lineBreak = "\u2028"
+)
+
+// snapshotLink is the line linking a bookmark to the snapshot of its page.
+var snapshotLink = regexp.MustCompile(`\n?\[` + snapshotLabel + `\]\(([^\s()]*)\)$`)
+
+// skippedElements are left out of snapshots, being page furniture, scripts or
+// media rather than content.
+var skippedElements = map[atom.Atom]bool{
+	atom.Aside:    true,
+	atom.Audio:    true,
+	atom.Button:   true,
+	atom.Canvas:   true,
+	atom.Footer:   true,
+	atom.Form:     true,
+	atom.Header:   true,
+	atom.Iframe:   true,
+	atom.Img:      true,
+	atom.Input:    true,
+	atom.Nav:      true,
+	atom.Noscript: true,
+	atom.Picture:  true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Video:    true,
+}
+
+// inlineElements are rendered as part of the paragraph they are in.
+var inlineElements = map[atom.Atom]bool{
+	atom.A:      true,
+	atom.Abbr:   true,
+	atom.B:      true,
+	atom.Cite:   true,
+	atom.Code:   true,
+	atom.Em:     true,
+	atom.I:      true,
+	atom.Label:  true,
+	atom.Mark:   true,
+	atom.Q:      true,
+	atom.Small:  true,
+	atom.Span:   true,
+	atom.Strong: true,
+	atom.Sub:    true,
+	atom.Sup:    true,
+	atom.Time:   true,
+}
+
+// SnapshotPath returns where the snapshot of the page at rawURL is saved for
+// the bookmarks file at notesPath.
+func SnapshotPath(notesPath, rawURL string) string {
+	return filepath.Join(filepath.Dir(notesPath), filepath.FromSlash(snapshotName(rawURL)))
+}
+
+// snapshotName returns the path of the snapshot of the page at rawURL relative
+// to the notes file, named after a hash of its normalised URL.
+func snapshotName(rawURL string) string {
+	sum := sha256.Sum256([]byte(normalizeURL(rawURL)))
+	return path.Join(snapshotsDir, hex.EncodeToString(sum[:8])+".md")
+}
+
+// saveSnapshot downloads the page at pageURL, saves a markdown copy of its
+// main content next to the notes file at notesPath, and returns the page's
+// metadata along with the copy's path relative to the notes file. Paragraphs
+// are wrapped at width.
+func saveSnapshot(
+	ctx context.Context,
+	notesPath, pageURL string,
+	width int,
+) (pageMeta, string, error) {
+	doc, base, err := fetchPage(ctx, pageURL, maxSnapshotSize)
+	if err != nil {
+		return pageMeta{}, "", err
+	}
+	meta := readPageMeta(doc, base)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", firstNonBlank(meta.title, pageURL))
+	fmt.Fprintf(&sb, "Saved from <%s> on %s.\n", pageURL, time.Now().Format(headingDateFormat))
+	if content := snapshotMarkdown(doc, base, width); content != "" {
+		fmt.Fprintf(&sb, "\n%s\n", content)
+	}
+	name := snapshotName(pageURL)
+	snapshotPath := SnapshotPath(notesPath, pageURL)
+	if err = os.MkdirAll(filepath.Dir(snapshotPath), 0o755); err != nil {
+		return pageMeta{}, "", err
+	}
+	if err = writeFileAtomic(snapshotPath, []byte(sb.String())); err != nil {
+		return pageMeta{}, "", err
+	}
+	return meta, name, nil
+}
+
+// snapshotMarkdown converts the main content of doc to markdown, leaving out
+// images and page furniture such as navigation. Links are resolved against
+// base, and paragraphs are wrapped at width.
+func snapshotMarkdown(doc *html.Node, base *url.URL, width int) string {
+	w := markdownWriter{base: base, width: width}
+	w.children(mainContent(doc))
+	w.flush()
+	return strings.Join(w.blocks, "\n\n")
+}
+
+// mainContent returns the element of doc that holds its main content: its
+// article, its main element, or its body, whichever comes first.
+func mainContent(doc *html.Node) *html.Node {
+	for _, match := range []func(*html.Node) bool{
+		func(n *html.Node) bool { return n.DataAtom == atom.Article },
+		func(n *html.Node) bool {
+			return n.DataAtom == atom.Main || strings.EqualFold(attr(n, "role"), "main")
+		},
+		func(n *html.Node) bool { return n.DataAtom == atom.Body },
+	} {
+		if n := findElement(doc, match); n != nil {
+			return n
+		}
+	}
+	return doc
+}
+
+// findElement returns the first element under n, in document order, that
+// matches.
+func findElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || skippedElements[c.DataAtom] {
+			continue
+		}
+		if match(c) {
+			return c
+		}
+		if found := findElement(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// markdownWriter converts HTML to markdown blocks. Inline content is
+// collected until the next block element starts.
+type markdownWriter struct {
+	base   *url.URL
+	blocks []string
+	inline strings.Builder
+	width  int
+}
+
+func (w *markdownWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+func (w *markdownWriter) node(n *html.Node) {
+	if n.Type == html.TextNode {
+		w.inline.WriteString(n.Data)
+		return
+	}
+	if n.Type != html.ElementNode || isHidden(n) {
+		return
+	}
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.flush()
+		if text := collapseSpace(w.inlineText(n)); text != "" {
+			level := int(n.Data[1] - '0')
+			w.block(strings.Repeat("#", level) + " " + text)
+		}
+	case atom.Pre:
+		w.flush()
+		if code := strings.Trim(rawText(n), "\n"); code != "" {
+			w.block("```\n" + code + "\n```")
+		}
+	case atom.Ul, atom.Ol:
+		w.flush()
+		w.block(w.list(n, ""))
+	case atom.Blockquote:
+		w.flush()
+		quote := markdownWriter{base: w.base, width: w.width - 2}
+		quote.children(n)
+		quote.flush()
+		if len(quote.blocks) > 0 {
+			text := strings.Join(quote.blocks, "\n\n")
+			w.block("> " + strings.ReplaceAll(text, "\n", "\n> "))
+		}
+	case atom.Hr:
+		w.flush()
+		w.block("---")
+	case atom.Br:
+		w.inline.WriteString(lineBreak)
+	default:
+		if skippedElements[n.DataAtom] {
+			return
+		}
+		if inlineElements[n.DataAtom] {
+			w.inline.WriteString(w.inlineNode(n))
+			return
+		}
+		// Anything else is treated as a block, such as paragraphs, divs and
+		// table rows.
+		w.flush()
+		w.children(n)
+		w.flush()
+	}
+}
+
+// flush turns the inline content collected so far into a paragraph.
+func (w *markdownWriter) flush() {
+	var lines []string
+	for _, l := range strings.Split(w.inline.String(), lineBreak) {
+		if l = collapseSpace(l); l != "" {
+			lines = append(lines, wordWrap(l, w.width))
+		}
+	}
+	w.inline.Reset()
+	w.block(strings.Join(lines, "\n"))
+}
+
+func (w *markdownWriter) block(text string) {
+	if text != "" {
+		w.blocks = append(w.blocks, text)
+	}
+}
+
+// list renders the list n, indenting its items by indent.
+func (w *markdownWriter) list(n *html.Node, indent string) string {
+	var items []string
+	number := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprint(number, ". ")
+			number++
+		}
+		var text strings.Builder
+		var nested []string
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.DataAtom == atom.Ul || c.DataAtom == atom.Ol:
+				nested = append(nested, w.list(c, indent+strings.Repeat(" ", len(marker))))
+			case c.Type == html.TextNode:
+				text.WriteString(c.Data)
+			case inlineElements[c.DataAtom] && !isHidden(c):
+				text.WriteString(w.inlineNode(c))
+			case c.Type == html.ElementNode && !skippedElements[c.DataAtom] && !isHidden(c):
+				// Paragraphs in an item run on, as items are a single line.
+				text.WriteString(" " + w.inlineText(c) + " ")
+			}
+		}
+		item := collapseSpace(text.String())
+		if item == "" && len(nested) == 0 {
+			continue
+		}
+		items = append(items, indent+marker+item)
+		items = append(items, nested...)
+	}
+	return strings.Join(items, "\n")
+}
+
+// inlineText renders the content of n as inline markdown.
+func (w *markdownWriter) inlineText(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			sb.WriteString(c.Data)
+		case c.Type == html.ElementNode && !skippedElements[c.DataAtom] && !isHidden(c):
+			sb.WriteString(w.inlineNode(c))
+		}
+	}
+	return sb.String()
+}
+
+func (w *markdownWriter) inlineNode(n *html.Node) string {
+	emphasise := func(marker string) string {
+		text := collapseSpace(w.inlineText(n))
+		if text == "" {
+			return ""
+		}
+		return marker + text + marker
+	}
+	switch n.DataAtom {
+	case atom.A:
+		text := collapseSpace(w.inlineText(n))
+		href := resolveURL(w.base, attr(n, "href"))
+		if text == "" || href == "" {
+			return text
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	case atom.B, atom.Strong:
+		return emphasise("**")
+	case atom.I, atom.Em:
+		return emphasise("_")
+	case atom.Code:
+		if code := strings.TrimSpace(rawText(n)); code != "" {
+			return "`" + code + "`"
+		}
+		return ""
+	case atom.Br:
+		return lineBreak
+	default:
+		return w.inlineText(n)
+	}
+}
+
+// isHidden reports whether n is marked as not being shown to readers.
+func isHidden(n *html.Node) bool {
+	for _, a := range n.Attr {
+		if a.Key == "hidden" || (a.Key == "aria-hidden" && a.Val == "true") {
+			return true
+		}
+	}
+	return false
+}
+
+// rawText returns all the text under n as is.
+func rawText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(rawText(c))
+	}
+	return sb.String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
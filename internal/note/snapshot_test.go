@@ -0,0 +1,105 @@
+package note
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	tests := []struct {
+		name     string
+		page     string
+		expected string
+	}{
+		{
+			name: "MainContentOnly",
+			page: `<body><nav><a href="/">Home</a></nav>
+<article><header><p>By someone</p></header>
+<h1>A post</h1>
+<p><b>Bold</b>, <em>emphasis</em>, <code>x := 1</code>
+with <a href="../about">a link</a>.</p>
+<img src="cat.png" alt="A cat">
+</article><footer>Copyright</footer></body>`,
+			expected: "# A post\n\n" +
+				"**Bold**, _emphasis_, `x := 1` with " +
+				"[a link](https://example.com/about).",
+		},
+		{
+			name: "Blocks",
+			page: `<main><h2>Lists</h2>
+<ul><li>One</li><li>Two<ol><li>Nested</li></ol></li></ul>
+<pre><code>func main() {
+	fmt.Println("hi")
+}</code></pre>
+<blockquote><p>Quoted</p><p>Twice</p></blockquote>
+<hr><p hidden>Hidden</p><p>Line<br>break</p></main>`,
+			expected: "## Lists\n\n" +
+				"- One\n- Two\n  1. Nested\n\n" +
+				"```\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n\n" +
+				"> Quoted\n> \n> Twice\n\n" +
+				"---\n\n" +
+				"Line\nbreak",
+		},
+		{
+			name: "QuoteWrapped",
+			page: `<blockquote><p>A quoted paragraph that is long enough to be wrapped before it
+reaches the edge of the page, less the marker.</p></blockquote>`,
+			expected: "> A quoted paragraph that is long enough to be wrapped before it reaches the\n" +
+				"> edge of the page, less the marker.",
+		},
+		{
+			name:     "Body",
+			page:     `<p>Just a paragraph</p><script>alert(1)</script>`,
+			expected: "Just a paragraph",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := parseHTML(strings.NewReader(tc.page), "text/html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := snapshotMarkdown(doc, base, wrapWidth); got != tc.expected {
+				t.Errorf("snapshotMarkdown() = %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBookmarkArchive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<title>Archived</title><article><p>Worth keeping</p></article>`)
+	}))
+	defer server.Close()
+	notesPath := filepath.Join(t.TempDir(), "notes.bookmark.md")
+	b := bookmark{archiveTo: notesPath}
+	md, err := b.toMarkdown(context.Background(), server.URL+"/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := snapshotName(server.URL + "/post")
+	expected := fmt.Sprintf("\n[Archived](%s/post)\\\ntags:\n[offline copy](%s)\n", server.URL, name)
+	if md != expected {
+		t.Errorf("toMarkdown() = %q, expected %q", md, expected)
+	}
+	snapshot, err := os.ReadFile(SnapshotPath(notesPath, server.URL+"/post/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(snapshot), "# Archived\n\nSaved from <"+server.URL+"/post> on ") ||
+		!strings.HasSuffix(string(snapshot), "\n\nWorth keeping\n") {
+		t.Errorf("the snapshot is %q", snapshot)
+	}
+	parsed := parseBookmarks([]byte(md))
+	if len(parsed) != 1 || parsed[0].snapshot != name || parsed[0].description != "" {
+		t.Errorf("parseBookmarks(%q) = %+v", md, parsed)
+	}
+}
@@ -1,56 +1,357 @@
 package note
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	textm "github.com/yuin/goldmark/text"
 
 	"github.com/chaitanyabsprip/note/internal/preview"
-	"github.com/muesli/reflow/wordwrap"
 )
 
-func sentenceCase(input string) string {
+// proseParser parses the markdown that notes are written in. Bare links are
+// recognised as links so that they are left alone.
+var proseParser = goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser()
+
+// formatProse capitalises the sentences in the paragraphs of the markdown
+// text and wraps them at width, or not at all if width is not positive. Code,
+// links and everything that is not a paragraph, such as fenced blocks,
+// headings and the markers of lists and quotes, are left as they are.
+func formatProse(text string, width int) string {
+	source := []byte(text)
+	doc := proseParser.Parse(textm.NewReader(source))
+	var out strings.Builder
+	prev := 0
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Kind() != ast.KindParagraph && n.Kind() != ast.KindTextBlock {
+			return ast.WalkContinue, nil
+		}
+		lines := n.Lines()
+		if lines.Len() == 0 {
+			return ast.WalkSkipChildren, nil
+		}
+		start, stop := lines.At(0).Start, lines.At(lines.Len()-1).Stop
+		out.Write(source[prev:start])
+		out.WriteString(formatParagraph(source, n, width))
+		if source[stop-1] == '\n' {
+			out.WriteString("\n")
+		}
+		prev = stop
+		return ast.WalkSkipChildren, nil
+	})
+	out.Write(source[prev:])
+	return out.String()
+}
+
+// paragraphFormatter tracks where sentences start in a paragraph, which
+// letters to capitalise and which bytes are to be kept as they are, such as
+// those of code and links.
+type paragraphFormatter struct {
+	source   []byte
+	verbatim map[int]bool
+	capital  map[int]bool
+	// sentenceStart is set until the first word of a sentence is seen, and
+	// sentenceEnd after punctuation that might end one.
+	sentenceStart bool
+	sentenceEnd   bool
+}
+
+// formatParagraph returns the paragraph n of source capitalised and wrapped,
+// without a trailing newline. Its lines after the first are prefixed the way
+// its first line is, with the markers of lists replaced by spaces.
+func formatParagraph(source []byte, n ast.Node, width int) string {
+	f := paragraphFormatter{
+		source:        source,
+		verbatim:      map[int]bool{},
+		capital:       map[int]bool{},
+		sentenceStart: true,
+	}
+	f.inline(n)
+	lines := n.Lines()
+	first := lines.At(0)
+	lineStart := bytes.LastIndexByte(source[:first.Start], '\n') + 1
+	indent := []rune(string(source[lineStart:first.Start]))
+	for i, r := range indent {
+		if r != '>' && !unicode.IsSpace(r) {
+			indent[i] = ' '
+		}
+	}
+	var words []string
+	var word strings.Builder
+	endWord := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for i := range lines.Len() {
+		line := lines.At(i)
+		text := strings.TrimRight(string(line.Value(source)), "\n")
+		for off := line.Start; off < line.Start+len(text); {
+			r, size := utf8.DecodeRune(source[off:])
+			switch {
+			case unicode.IsSpace(r) && !f.verbatim[off]:
+				endWord()
+			case f.capital[off]:
+				word.WriteRune(unicode.ToUpper(r))
+			default:
+				word.Write(source[off : off+size])
+			}
+			off += size
+		}
+		endWord()
+		// Hard line breaks are kept, and so is the "  " that makes them.
+		if i < lines.Len()-1 && (strings.HasSuffix(text, "  ") || strings.HasSuffix(text, "\\")) {
+			if strings.HasSuffix(text, "  ") {
+				words[len(words)-1] += "  "
+			}
+			words = append(words, "\n")
+		}
+	}
+	if width > 0 {
+		width = max(width-runewidth.StringWidth(string(indent)), 1)
+	}
+	return wrapWords(words, width, string(indent))
+}
+
+// inline classifies the bytes of the inline content of n.
+func (f *paragraphFormatter) inline(n ast.Node) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			f.text(c)
+		case *ast.Emphasis, *east.Strikethrough:
+			f.inline(c)
+		case *east.TaskCheckBox:
+		default:
+			f.keep(c)
+			f.sentenceStart, f.sentenceEnd = false, false
+		}
+	}
+}
+
+// text marks the first letter of every sentence in t to be capitalised.
+func (f *paragraphFormatter) text(t *ast.Text) {
+	for off := t.Segment.Start; off < t.Segment.Stop; {
+		r, size := utf8.DecodeRune(f.source[off:])
+		switch {
+		case unicode.IsSpace(r):
+			f.sentenceStart = f.sentenceStart || f.sentenceEnd
+			f.sentenceEnd = false
+		case f.sentenceStart:
+			f.capital[off] = unicode.IsLetter(r) && !isTechnical(f.word(off, t.Segment.Stop))
+			f.sentenceStart = false
+		default:
+			f.sentenceEnd = strings.ContainsRune(".!?", r)
+		}
+		off += size
+	}
+	if t.SoftLineBreak() || t.HardLineBreak() {
+		f.sentenceStart = f.sentenceStart || f.sentenceEnd
+		f.sentenceEnd = false
+	}
+}
+
+// keep marks the text under n to be kept as it is.
+func (f *paragraphFormatter) keep(n ast.Node) {
+	mark := func(s textm.Segment) {
+		for off := s.Start; off < s.Stop; off++ {
+			f.verbatim[off] = true
+		}
+	}
+	switch n := n.(type) {
+	case *ast.Text:
+		mark(n.Segment)
+	case *ast.RawHTML:
+		for i := range n.Segments.Len() {
+			mark(n.Segments.At(i))
+		}
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		f.keep(c)
+	}
+}
+
+// word returns the word starting at off, up to stop, without the punctuation
+// that follows it.
+func (f *paragraphFormatter) word(off, stop int) string {
+	word := string(f.source[off:stop])
+	if end := strings.IndexFunc(word, unicode.IsSpace); end >= 0 {
+		word = word[:end]
+	}
+	return strings.TrimRight(word, ".,;:!?)\"'")
+}
+
+// isTechnical reports whether word looks like a path, an address or an
+// identifier, which keep their case even at the start of a sentence.
+func isTechnical(word string) bool {
+	return strings.ContainsAny(word, "/\\.@_:=")
+}
+
+// blockMarker matches the words that start a block at the beginning of a
+// line, such as the markers of lists, headings and quotes.
+var blockMarker = regexp.MustCompile("^(>|#{1,6}$|\\d{1,9}[.)]$|[-+*=_]+$|```|~~~)")
+
+// startsBlock reports whether a line starting with word would start a block
+// rather than carry on a paragraph, so that lines are not broken before it.
+func startsBlock(word string) bool {
+	return blockMarker.MatchString(word)
+}
+
+// wrapWords joins words into lines no wider than width columns, prefixing
+// every line after the first with indent. A "\n" word breaks the line, and so
+// does nothing else if width is not positive. Lines are never broken before a
+// word that would start a block, which is kept on the line before instead.
+func wrapWords(words []string, width int, indent string) string {
 	var sb strings.Builder
-	sentences := strings.Split(input, ". ")
-	for _, sentence := range sentences {
-		sentence = strings.TrimSpace(sentence)
-		if len(sentence) == 0 {
+	lineWidth := 0
+	for _, word := range words {
+		if word == "\n" {
+			sb.WriteString("\n" + indent)
+			lineWidth = 0
 			continue
 		}
-		sb.WriteString(strings.ToUpper(string(sentence[0])))
-		sb.WriteString(sentence[1:])
-		sb.WriteString("\n")
+		for i, piece := range splitWide(word) {
+			space := " "
+			if i > 0 {
+				space = ""
+			}
+			pieceWidth := runewidth.StringWidth(piece)
+			switch {
+			case lineWidth == 0:
+			case width > 0 && lineWidth+len(space)+pieceWidth > width && (i > 0 || !startsBlock(word)):
+				sb.WriteString("\n" + indent)
+				lineWidth = 0
+			default:
+				sb.WriteString(space)
+				lineWidth += len(space)
+			}
+			sb.WriteString(piece)
+			lineWidth += pieceWidth
+		}
+	}
+	return sb.String()
+}
+
+// closingPunctuation are the wide marks that end a phrase, which a line is not
+// to start with.
+const closingPunctuation = "。、，．！？：；」』）】〉》"
+
+// splitWide splits word between the wide characters in it, such as those of
+// CJK text that is written without spaces, where lines may be broken too. Wide
+// closing punctuation stays with the character before it.
+func splitWide(word string) []string {
+	var pieces []string
+	start := 0
+	prevWide := false
+	for i, r := range word {
+		wide := runewidth.RuneWidth(r) == 2
+		if i > start && prevWide && wide && !strings.ContainsRune(closingPunctuation, r) {
+			pieces = append(pieces, word[start:i])
+			start = i
+		}
+		prevWide = wide
+	}
+	return append(pieces, word[start:])
+}
+
+// capitalise upper-cases the first letter of s.
+func capitalise(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || !unicode.IsLower(r) {
+		return s
 	}
-	return strings.TrimSpace(sb.String())
+	return string(unicode.ToUpper(r)) + s[size:]
 }
 
+// wrapWidth is the width text is wrapped at unless the note sets another.
 const wrapWidth = 80
 
+// NoWrap is the width that keeps text from being wrapped, for editors that
+// wrap lines as they show them.
+const NoWrap = -1
+
+// columns returns the width to wrap at for a note whose width is set to
+// width, zero meaning the default.
+func columns(width int) int {
+	if width == 0 {
+		return wrapWidth
+	}
+	return width
+}
+
+// headingDateFormat is the layout of the date headings that group todos,
+// bookmarks and notes by the day they were written.
+const headingDateFormat = "Mon, 02 Jan 2006"
+
+// fencedBlock puts text in a fenced code block, indenting every line of it by
+// indent. The fence is made longer than any run of backticks in text.
+func fencedBlock(text, indent string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	lines := strings.Split(fence+"\n"+text+"\n"+fence, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// wordWrap wraps every line of text at lineWidth columns, as it is displayed
+// in a terminal, so that wide characters such as CJK and emoji count for two.
+// The spacing between words is kept, except where lines are broken, and lines
+// are not broken before a word that would start a block. Text is not wrapped at
+// all if lineWidth is not positive.
 func wordWrap(text string, lineWidth int) string {
-	return wordwrap.String(text, lineWidth)
-	// lines := strings.Split(text, "\n")
-	// wrapped := ""
-	// for _, line := range lines {
-	// 	words := strings.Fields(strings.TrimSpace(line))
-	// 	if len(words) == 0 {
-	// 		wrapped += line + "\n"
-	// 		continue
-	// 	}
-	// 	currLine := words[0]
-	// 	for _, word := range words[1:] {
-	// 		if len(currLine)+len(word) <= lineWidth-3 {
-	// 			currLine += " " + word
-	// 		} else {
-	// 			wrapped += currLine + "\n"
-	// 			currLine = word
-	// 		}
-	// 	}
-	// 	if currLine != "" {
-	// 		wrapped += currLine + "\n"
-	// 	}
-	// }
-	// return wrapped
+	if lineWidth <= 0 {
+		return text
+	}
+	var sb strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		width := 0
+		for line != "" {
+			word := strings.TrimLeftFunc(line, unicode.IsSpace)
+			space := line[:len(line)-len(word)]
+			line = ""
+			if end := strings.IndexFunc(word, unicode.IsSpace); end >= 0 {
+				word, line = word[:end], word[end:]
+			}
+			for i, piece := range splitWide(word) {
+				if i > 0 {
+					space = ""
+				}
+				pieceWidth := runewidth.StringWidth(piece)
+				if width > 0 && piece != "" && width+len(space)+pieceWidth > lineWidth &&
+					(i > 0 || !startsBlock(word)) {
+					sb.WriteString("\n")
+					width = 0
+				} else {
+					sb.WriteString(space)
+					width += runewidth.StringWidth(space)
+				}
+				sb.WriteString(piece)
+				width += pieceWidth
+			}
+		}
+	}
+	return sb.String()
 }
 
 func addHeading(body string, file *os.File) (string, error) {
@@ -73,7 +374,7 @@ func newHeading(file *os.File) (string, error) {
 	lines := strings.Split(content, "\n")
 	lHeading := lastHeading(lines)
 	prevTime := strings.TrimPrefix(lHeading, "## ")
-	currTime := time.Now().Format("Mon, 02 Jan 2006")
+	currTime := time.Now().Format(headingDateFormat)
 	if currTime != prevTime || lHeading == "" {
 		return fmt.Sprint("## ", currTime), nil
 	}
@@ -0,0 +1,184 @@
+package note
+
+import "testing"
+
+func TestFormatProse(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		expected string
+	}{
+		{
+			name:     "Sentences",
+			text:     "first one. second one! third? été est là. done",
+			width:    80,
+			expected: "First one. Second one! Third? Été est là. Done",
+		},
+		{
+			name:     "Wrapping",
+			text:     "one two three four five six",
+			width:    10,
+			expected: "One two\nthree four\nfive six",
+		},
+		{
+			name:     "WideCharacters",
+			text:     "日本語 日本語 日本語",
+			width:    13,
+			expected: "日本語 日本語\n日本語",
+		},
+		{
+			name:     "WideCharactersWithoutSpaces",
+			text:     "日本語の文章は、空白なしで書かれる。",
+			width:    12,
+			expected: "日本語の文章\nは、空白なし\nで書かれる。",
+		},
+		{
+			name:     "CodeSpans",
+			text:     "run `go test ./...` now. `make all` first",
+			width:    14,
+			expected: "Run\n`go test ./...`\nnow.\n`make all`\nfirst",
+		},
+		{
+			name:     "Links",
+			text:     "see [the docs page](https://go.dev/doc/) and https://example.com/a.b",
+			width:    80,
+			expected: "See [the docs page](https://go.dev/doc/) and https://example.com/a.b",
+		},
+		{
+			name:     "TechnicalWords",
+			text:     "main.go is long. user@example.com wrote it. foo_bar too",
+			width:    80,
+			expected: "main.go is long. user@example.com wrote it. foo_bar too",
+		},
+		{
+			name:     "Lists",
+			text:     "- first item. which wraps\n- second\n\n1. numbered",
+			width:    14,
+			expected: "- First item.\n  Which wraps\n- Second\n\n1. Numbered",
+		},
+		{
+			name:     "Quotes",
+			text:     "> quoted words that wrap",
+			width:    12,
+			expected: "> Quoted\n> words that\n> wrap",
+		},
+		{
+			name:     "FencedBlocks",
+			text:     "look. here\n\n```go\nfmt.println(\"hi. there\")\n```\nafter",
+			width:    80,
+			expected: "Look. Here\n\n```go\nfmt.println(\"hi. there\")\n```\nAfter",
+		},
+		{
+			name:     "HardLineBreaks",
+			text:     "first line.  \nsecond line\\\nthird",
+			width:    80,
+			expected: "First line.  \nSecond line\\\nthird",
+		},
+		{
+			name:     "Emphasis",
+			text:     "**bold** start. _then_ more",
+			width:    80,
+			expected: "**Bold** start. _Then_ more",
+		},
+		{
+			name:     "NoWrap",
+			text:     "a line. that is not wrapped",
+			width:    NoWrap,
+			expected: "A line. That is not wrapped",
+		},
+		{
+			name:     "BlockMarkers",
+			text:     "see the docs for more. - a list? no. # not a heading > nor a quote 1. nor a list",
+			width:    22,
+			expected: "See the docs for more. -\na list? No. # not a\nheading > nor a quote 1.\nNor a list",
+		},
+		{
+			name:     "Headings",
+			text:     "# a heading. kept\n\ntext",
+			width:    80,
+			expected: "# a heading. kept\n\nText",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatProse(tc.text, tc.width); got != tc.expected {
+				t.Errorf("formatProse(%q, %d) = %q, expected %q", tc.text, tc.width, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWordWrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		expected string
+	}{
+		{
+			name:     "Spacing",
+			text:     "Mon Jan  3 words  to wrap",
+			width:    14,
+			expected: "Mon Jan  3\nwords  to wrap",
+		},
+		{
+			name:     "WideCharacters",
+			text:     "中文 标题 很长 的书签",
+			width:    11,
+			expected: "中文 标题\n很长 的书签",
+		},
+		{
+			name:     "WideCharactersWithoutSpaces",
+			text:     "日本語の文章は、空白なしで書かれる。",
+			width:    12,
+			expected: "日本語の文章\nは、空白なし\nで書かれる。",
+		},
+		{
+			name:     "Emoji",
+			text:     "🎉🎉 party 🎉🎉",
+			width:    10,
+			expected: "🎉🎉 party\n🎉🎉",
+		},
+		{
+			name:     "Lines",
+			text:     "first line\n\n  indented line",
+			width:    10,
+			expected: "first line\n\n  indented\nline",
+		},
+		{
+			name:     "BlockMarkers",
+			text:     "a title - with a dash",
+			width:    7,
+			expected: "a title -\nwith a\ndash",
+		},
+		{
+			name:     "NoWrap",
+			text:     "a line that is not wrapped",
+			width:    NoWrap,
+			expected: "a line that is not wrapped",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := wordWrap(tc.text, tc.width); got != tc.expected {
+				t.Errorf("wordWrap(%q, %d) = %q, expected %q", tc.text, tc.width, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCapitalise(t *testing.T) {
+	for text, expected := range map[string]string{
+		"":           "",
+		"notes":      "Notes",
+		"été":        "Été",
+		"Todo":       "Todo",
+		"1 thing":    "1 thing",
+		"ünïcode ok": "Ünïcode ok",
+	} {
+		if got := capitalise(text); got != expected {
+			t.Errorf("capitalise(%q) = %q, expected %q", text, got, expected)
+		}
+	}
+}
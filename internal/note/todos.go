@@ -0,0 +1,487 @@
+package note
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// doneStampFormat is the layout of the completion time that CompleteTodo can
+// stamp on a todo.
+const doneStampFormat = "2006-01-02 15:04"
+
+var (
+	doneStamp = regexp.MustCompile(`\s*\(done (\d{4}-\d{2}-\d{2} \d{2}:\d{2})\)`)
+	dueDate   = regexp.MustCompile(`(^|\s)@(\d{4}-\d{2}-\d{2})\b`)
+	// storedPriority matches priorities in the form resolvePriorities
+	// writes them.
+	storedPriority = regexp.MustCompile(`(^|\s)!(high|med|low)\b`)
+)
+
+// Completed describes a todo ticked off by CompleteTodo.
+type Completed struct {
+	// Text is the text of the completed todo.
+	Text string
+	// Parent is the text of the todo's parent if ticking it off left the
+	// parent pending with every subtask done. It is empty otherwise.
+	Parent string
+	// ParentPosition is the parent's position among pending todos, which
+	// can be passed back to CompleteTodo to tick it off as well.
+	ParentPosition int
+}
+
+// CompleteTodo ticks off the pending todo matching query in the todos file at
+// notesPath. query is either the todo's position among pending todos, starting
+// at 1, or text that fuzzily matches it. If stamp is set, the completion time
+// is appended to the todo.
+func CompleteTodo(notesPath, query string, stamp bool, now time.Time) (Completed, error) {
+	suffix := ""
+	if stamp {
+		suffix = fmt.Sprintf(" (done %s)", now.Format(doneStampFormat))
+	}
+	t, data, err := markTodo(notesPath, query, true, suffix)
+	if err != nil {
+		return Completed{}, err
+	}
+	completed := Completed{Text: t.text}
+	todos := parseTodos(data)
+	i := slices.IndexFunc(todos, func(u parsedTodo) bool { return u.start == t.start })
+	if i < 0 || todos[i].parent < 0 || todos[todos[i].parent].done {
+		return completed, nil
+	}
+	parent := todos[i].parent
+	position := 0
+	for j, u := range todos {
+		if !u.done {
+			position++
+		}
+		if j == parent {
+			completed.ParentPosition = position
+		}
+		if u.parent == parent && !u.done {
+			return completed, nil
+		}
+	}
+	completed.Parent = todos[parent].text
+	return completed, nil
+}
+
+// ReopenTodo unticks the completed todo matching query in the todos file at
+// notesPath, removing its completion time if it has one. query is either the
+// todo's position among completed todos, starting at 1, or text that fuzzily
+// matches it. It returns the text of the reopened todo.
+func ReopenTodo(notesPath, query string) (string, error) {
+	t, _, err := markTodo(notesPath, query, false, "")
+	return t.text, err
+}
+
+// markTodo sets the checkbox of the todo matching query, looking only at todos
+// that are not already in that state, and appends suffix to it. It returns the
+// todo as it was before along with the new contents of the file.
+func markTodo(notesPath, query string, done bool, suffix string) (parsedTodo, []byte, error) {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return parsedTodo{}, nil, err
+	}
+	var candidates []parsedTodo
+	for _, t := range parseTodos(data) {
+		if t.done != done {
+			candidates = append(candidates, t)
+		}
+	}
+	t, err := findTodo(candidates, query)
+	if err != nil {
+		return parsedTodo{}, nil, err
+	}
+	item := bytes.Clone(data[t.start:t.textEnd])
+	box := bytes.IndexByte(item, '[')
+	item[box+1] = ' '
+	if done {
+		item[box+1] = 'x'
+	}
+	if !done {
+		item = doneStamp.ReplaceAll(item, nil)
+	}
+	item = append(item, suffix...)
+	var out bytes.Buffer
+	out.Write(data[:t.start])
+	out.Write(item)
+	out.Write(data[t.textEnd:])
+	return t, out.Bytes(), writeFileAtomic(notesPath, out.Bytes())
+}
+
+// AddSubtask writes content as a subtask of the pending todo matching
+// parentQuery in the todos file at notesPath, after the todo's existing
+// subtasks, wrapped at width. parentQuery is resolved the same way as
+// CompleteTodo's query. It returns the text of the parent.
+func AddSubtask(notesPath, parentQuery, content string, width int) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", errors.New("nothing to note here")
+	}
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return "", err
+	}
+	todos := parseTodos(data)
+	var pending []parsedTodo
+	for _, t := range todos {
+		if !t.done {
+			pending = append(pending, t)
+		}
+	}
+	parent, err := findTodo(pending, parentQuery)
+	if err != nil {
+		return "", err
+	}
+	indent := strings.Repeat(" ", parent.indent+2)
+	// The subtask is wrapped short of width by its indent, which every one of
+	// its lines is prefixed with.
+	if width = columns(width); width > 0 {
+		width = max(width-len(indent), 1)
+	}
+	markdown, err := todo{width: width}.toMarkdown(context.Background(), content)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSuffix(markdown, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = indent + line
+	}
+	end := subtreeEnd(todos, slices.IndexFunc(todos, func(t parsedTodo) bool {
+		return t.start == parent.start
+	}))
+	var out bytes.Buffer
+	out.Write(data[:end])
+	if end > 0 && data[end-1] != '\n' {
+		out.WriteString("\n")
+	}
+	fmt.Fprintln(&out, strings.Join(lines, "\n"))
+	out.Write(data[end:])
+	return parent.text, writeFileAtomic(notesPath, out.Bytes())
+}
+
+// findTodo resolves query to one of todos. A number picks the todo at that
+// position, starting at 1. Anything else is matched against the todos' text,
+// preferring substring matches over ones where the query's characters merely
+// appear in order.
+func findTodo(todos []parsedTodo, query string) (parsedTodo, error) {
+	query = strings.TrimSpace(query)
+	if n, err := strconv.Atoi(query); err == nil {
+		if n < 1 || n > len(todos) {
+			return parsedTodo{}, fmt.Errorf("no todo at position %d, there are %d", n, len(todos))
+		}
+		return todos[n-1], nil
+	}
+	needle := strings.ToLower(query)
+	if needle == "" {
+		return parsedTodo{}, errors.New("which todo?")
+	}
+	var matches []parsedTodo
+	for _, match := range []func(string) bool{
+		func(text string) bool { return text == needle },
+		func(text string) bool { return strings.Contains(text, needle) },
+		func(text string) bool { return isSubsequence(needle, text) },
+	} {
+		for _, t := range todos {
+			if match(strings.ToLower(t.text)) {
+				matches = append(matches, t)
+			}
+		}
+		if len(matches) > 0 {
+			break
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return parsedTodo{}, fmt.Errorf("no todo matches %q", query)
+	case 1:
+		return matches[0], nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%q matches %d todos:", query, len(matches))
+	for _, t := range matches {
+		fmt.Fprint(&sb, "\n  - ", t.text)
+	}
+	return parsedTodo{}, fmt.Errorf("%s", sb.String())
+}
+
+// isSubsequence reports whether the runes of needle appear in text in order.
+func isSubsequence(needle, text string) bool {
+	runes := []rune(needle)
+	for _, r := range text {
+		if len(runes) > 0 && r == runes[0] {
+			runes = runes[1:]
+		}
+	}
+	return len(runes) == 0
+}
+
+// parseAnnotations moves the annotations that note appends to a todo's text,
+// such as its completion time, out of the text and into t.
+func (t *todo) parseAnnotations() {
+	if m := doneStamp.FindStringSubmatch(t.text); m != nil {
+		t.doneAt, _ = time.ParseInLocation(doneStampFormat, m[1], time.Local)
+		t.text = doneStamp.ReplaceAllString(t.text, "")
+	}
+	if m := fromMarker.FindStringSubmatch(t.text); m != nil {
+		t.from, _ = time.ParseInLocation(time.DateOnly, m[1], time.Local)
+		t.text = fromMarker.ReplaceAllString(t.text, "")
+	}
+	if m := storedPriority.FindAllStringSubmatch(t.text, -1); m != nil {
+		t.priority, _ = ParsePriority(m[len(m)-1][2])
+		t.text = strings.Join(strings.Fields(storedPriority.ReplaceAllString(t.text, "")), " ")
+	}
+	if m := dueDate.FindAllStringSubmatch(t.text, -1); m != nil {
+		t.due, _ = time.ParseInLocation(time.DateOnly, m[len(m)-1][2], time.Local)
+		t.text = strings.Join(strings.Fields(dueDate.ReplaceAllString(t.text, "")), " ")
+	}
+}
+
+// TodoFilter narrows down the todos printed by ListTodos. Zero values match
+// every pending todo.
+type TodoFilter struct {
+	Due     time.Time
+	Overdue bool
+}
+
+func (f TodoFilter) matches(t parsedTodo, now time.Time) bool {
+	if f.Overdue && (t.due.IsZero() || !isBeforeDay(t.due, now)) {
+		return false
+	}
+	return f.Due.IsZero() || sameDay(t.due, f.Due)
+}
+
+// ListTodos writes the pending todos in the file at notesPath that match
+// filter to w, most urgent first and oldest first within the same priority.
+// Pending subtasks are listed under their todo, which shows how many of its
+// subtasks are done. Each todo is shown with its position among pending todos
+// in the file, which is what CompleteTodo expects.
+func ListTodos(w io.Writer, notesPath string, filter TodoFilter, now time.Time) error {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return err
+	}
+	todos := parseTodos(data)
+	positions := make([]int, len(todos))
+	shown := make([]bool, len(todos))
+	subtasks := make([]int, len(todos))
+	subtasksDone := make([]int, len(todos))
+	position := 0
+	for i, t := range todos {
+		if t.parent >= 0 {
+			subtasks[t.parent]++
+			if t.done {
+				subtasksDone[t.parent]++
+			}
+		}
+		if t.done {
+			continue
+		}
+		position++
+		positions[i] = position
+		shown[i] = filter.matches(t, now) || (t.parent >= 0 && shown[t.parent])
+	}
+	var roots []int
+	for i, t := range todos {
+		if shown[i] && (t.parent < 0 || !shown[t.parent]) {
+			roots = append(roots, i)
+		}
+	}
+	slices.SortStableFunc(roots, func(a, b int) int {
+		if todos[a].priority != todos[b].priority {
+			return cmp.Compare(todos[b].priority, todos[a].priority)
+		}
+		return todos[a].since().Compare(todos[b].since())
+	})
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tPRIORITY\tTODO\tDUE")
+	for _, root := range roots {
+		for i := root; i == root || (i < len(todos) && isDescendant(todos, i, root)); i++ {
+			if !shown[i] {
+				continue
+			}
+			t := todos[i]
+			text := strings.Repeat("  ", depth(todos, i, root)) + t.text
+			if subtasks[i] > 0 {
+				text += fmt.Sprintf(" (%d/%d)", subtasksDone[i], subtasks[i])
+			}
+			due := ""
+			if !t.due.IsZero() {
+				due = t.due.Format(time.DateOnly)
+				if isBeforeDay(t.due, now) {
+					due += " (overdue)"
+				}
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", positions[i], t.priority, text, due)
+		}
+	}
+	return tw.Flush()
+}
+
+// depth returns how many levels the i-th todo is nested under the root-th.
+func depth(todos []parsedTodo, i, root int) int {
+	d := 0
+	for ; i != root && i >= 0; i = todos[i].parent {
+		d++
+	}
+	return d
+}
+
+// since returns the day the todo was first written down, following it back
+// through rollovers.
+func (t parsedTodo) since() time.Time {
+	if !t.from.IsZero() {
+		return t.from
+	}
+	return t.section.date
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// rolloverOnNewDay runs RolloverTodos unless the todos file at notesPath
+// already has a section for today.
+func rolloverOnNewDay(notesPath string, now time.Time) error {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return err
+	}
+	today := now.Format(headingDateFormat)
+	for _, s := range parseSections(splitLines(data)) {
+		if s.heading == today {
+			return nil
+		}
+	}
+	_, err = RolloverTodos(notesPath, false, now)
+	return err
+}
+
+// RolloverTodos carries the pending todos from earlier days over to today's
+// section of the todos file at notesPath, creating the section if needed. Each
+// todo is marked with the day it came from and brings its subtasks along.
+// Todos are moved unless keep is set, in which case they are copied and left
+// in place as well. Days that are left without any content are removed. It
+// returns the number of todos carried over.
+func RolloverTodos(notesPath string, keep bool, now time.Time) (int, error) {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return 0, err
+	}
+	today := now.Format(headingDateFormat)
+	todos := parseTodos(data)
+	carried := map[string]bool{}
+	for _, t := range todos {
+		if t.section.heading == today && !t.done {
+			carried[t.text] = true
+		}
+	}
+	var stale []span
+	var items bytes.Buffer
+	for i, t := range todos {
+		if t.done || t.parent >= 0 || t.section.date.IsZero() ||
+			!isBeforeDay(t.section.date, now) || carried[t.text] {
+			continue
+		}
+		carried[t.text] = true
+		end := subtreeEnd(todos, i)
+		stale = append(stale, span{t.start, end})
+		item := data[t.start:t.textEnd]
+		items.Write(item)
+		if !fromMarker.Match(item) {
+			fmt.Fprintf(&items, " (from %s)", t.section.date.Format(time.DateOnly))
+		}
+		items.Write(data[t.textEnd:end])
+		if data[end-1] != '\n' {
+			items.WriteString("\n")
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+	if !keep {
+		data = removeSpans(data, stale)
+		data = removeEmptySections(data)
+	}
+	data = appendToSection(data, today, items.Bytes())
+	return len(stale), writeFileAtomic(notesPath, data)
+}
+
+var fromMarker = regexp.MustCompile(`\s*\(from (\d{4}-\d{2}-\d{2})\)`)
+
+// isBeforeDay reports whether t falls on a calendar day before now.
+func isBeforeDay(t, now time.Time) bool {
+	y, m, d := now.Date()
+	return t.Before(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+}
+
+// removeSpans cuts spans, given in file order, out of data.
+func removeSpans(data []byte, spans []span) []byte {
+	var out bytes.Buffer
+	prev := 0
+	for _, s := range spans {
+		out.Write(data[prev:s.start])
+		prev = s.end
+	}
+	out.Write(data[prev:])
+	return out.Bytes()
+}
+
+// removeEmptySections drops the date sections in data that have nothing but
+// blank lines under their heading.
+func removeEmptySections(data []byte) []byte {
+	var out bytes.Buffer
+	prev := 0
+	for _, s := range parseSections(splitLines(data)) {
+		_, body, _ := bytes.Cut(data[s.start:s.end], []byte("\n"))
+		if !isBlank(string(body)) {
+			continue
+		}
+		out.Write(data[prev:s.start])
+		prev = s.end
+	}
+	out.Write(data[prev:])
+	return out.Bytes()
+}
+
+// appendToSection adds items to the end of the date section with the given
+// heading, creating the section at the end of data if it does not exist.
+func appendToSection(data []byte, heading string, items []byte) []byte {
+	var section *dateSection
+	sections := parseSections(splitLines(data))
+	for i := range sections {
+		if sections[i].heading == heading {
+			section = &sections[i]
+		}
+	}
+	var out bytes.Buffer
+	if section == nil {
+		out.Write(bytes.TrimRight(data, "\n"))
+		fmt.Fprintf(&out, "\n\n## %s\n\n", heading)
+		out.Write(items)
+		return out.Bytes()
+	}
+	end := section.start + len(bytes.TrimRight(data[section.start:section.end], "\n"))
+	out.Write(data[:end])
+	out.WriteString("\n")
+	if end == section.start+len("## "+heading) {
+		out.WriteString("\n")
+	}
+	out.Write(items)
+	if end < len(data) {
+		out.Write(data[end+1:])
+	}
+	return out.Bytes()
+}
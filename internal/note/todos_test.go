@@ -0,0 +1,450 @@
+package note
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const todosFixture = `# Todo
+
+## Fri, 16 Oct 2026
+
+- [ ] Finish writing documentation
+- [x] Review PRs
+- [ ] A todo long enough to be wrapped onto the next line a todo long enough to be
+wrapped onto the next line
+
+## Sat, 17 Oct 2026
+
+- [ ] Write release notes
+`
+
+func TestCompleteTodo(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 9, 30, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		query    string
+		stamp    bool
+		expected string
+		text     string
+		wantErr  bool
+	}{
+		{
+			name:  "ByPosition",
+			query: "3",
+			expected: strings.Replace(
+				todosFixture,
+				"- [ ] Write release notes",
+				"- [x] Write release notes",
+				1,
+			),
+			text: "Write release notes",
+		},
+		{
+			name:  "WrappedWithStamp",
+			query: "2",
+			stamp: true,
+			expected: strings.Replace(
+				strings.Replace(todosFixture, "- [ ] A todo long", "- [x] A todo long", 1),
+				"wrapped onto the next line\n\n",
+				"wrapped onto the next line (done 2026-10-17 09:30)\n\n",
+				1,
+			),
+			text: "A todo long enough to be wrapped onto the next line a todo long enough to " +
+				"be wrapped onto the next line",
+		},
+		{
+			name:  "BySubstring",
+			query: "DOCUMENTATION",
+			expected: strings.Replace(
+				todosFixture,
+				"- [ ] Finish writing",
+				"- [x] Finish writing",
+				1,
+			),
+			text: "Finish writing documentation",
+		},
+		{
+			name:  "BySubsequence",
+			query: "rls nts",
+			expected: strings.Replace(
+				todosFixture,
+				"- [ ] Write release notes",
+				"- [x] Write release notes",
+				1,
+			),
+			text: "Write release notes",
+		},
+		{
+			name:    "Ambiguous",
+			query:   "writ",
+			wantErr: true,
+		},
+		{
+			name:    "AlreadyDone",
+			query:   "review",
+			wantErr: true,
+		},
+		{
+			name:    "OutOfRange",
+			query:   "4",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, todosFixture)
+			completed, err := CompleteTodo(path, tc.query, tc.stamp, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CompleteTodo() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if completed.Text != tc.text {
+				t.Errorf("CompleteTodo() = %q, expected %q", completed.Text, tc.text)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("CompleteTodo() wrote:\n%s\nexpected:\n%s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestReopenTodo(t *testing.T) {
+	path := writeFixture(t, todosFixture)
+	now := time.Date(2026, time.October, 17, 9, 30, 0, 0, time.Local)
+	if _, err := CompleteTodo(path, "release", true, now); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos := parseTodos(data)
+	last := todos[len(todos)-1]
+	if !last.done || !last.doneAt.Equal(now) || last.text != "Write release notes" {
+		t.Fatalf("parseTodos() = %+v after completing", last.todo)
+	}
+	text, err := ReopenTodo(path, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Write release notes" {
+		t.Errorf("ReopenTodo() = %q, expected %q", text, "Write release notes")
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != todosFixture {
+		t.Errorf("ReopenTodo() wrote:\n%s\nexpected:\n%s", got, todosFixture)
+	}
+}
+
+func TestRolloverTodos(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 8, 0, 0, 0, time.Local)
+	wrapped := "- [ ] A todo long enough to be wrapped onto the next line a todo long enough to be\n" +
+		"wrapped onto the next line"
+	tests := []struct {
+		name     string
+		input    string
+		keep     bool
+		expected string
+		count    int
+	}{
+		{
+			name:  "MovesIntoNewSection",
+			input: todosFixture,
+			expected: "# Todo\n\n## Fri, 16 Oct 2026\n\n- [x] Review PRs\n\n" +
+				"## Sun, 18 Oct 2026\n\n" +
+				"- [ ] Finish writing documentation (from 2026-10-16)\n" +
+				wrapped + " (from 2026-10-16)\n" +
+				"- [ ] Write release notes (from 2026-10-17)\n",
+			count: 3,
+		},
+		{
+			name:  "CopiesIntoExistingSection",
+			input: todosFixture + "\n## Sun, 18 Oct 2026\n\n- [ ] Write release notes (from 2026-10-17)\n",
+			keep:  true,
+			expected: todosFixture + "\n## Sun, 18 Oct 2026\n\n" +
+				"- [ ] Write release notes (from 2026-10-17)\n" +
+				"- [ ] Finish writing documentation (from 2026-10-16)\n" +
+				wrapped + " (from 2026-10-16)\n",
+			count: 2,
+		},
+		{
+			name:  "KeepsOriginalDay",
+			input: "# Todo\n\n## Sat, 17 Oct 2026\n\n- [ ] Old (from 2026-10-01)\n",
+			expected: "# Todo\n\n## Sun, 18 Oct 2026\n\n" +
+				"- [ ] Old (from 2026-10-01)\n",
+			count: 1,
+		},
+		{
+			name:     "NothingToCarry",
+			input:    "# Todo\n\n## Sun, 18 Oct 2026\n\n- [ ] Today\n",
+			expected: "# Todo\n\n## Sun, 18 Oct 2026\n\n- [ ] Today\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, tc.input)
+			count, err := RolloverTodos(path, tc.keep, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tc.count {
+				t.Errorf("RolloverTodos() = %d, expected %d", count, tc.count)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("RolloverTodos() wrote:\n%s\nexpected:\n%s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestListTodos(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 9, 0, 0, 0, time.Local)
+	fixture := "# Todo\n\n## Thu, 15 Oct 2026\n\n" +
+		"- [ ] Send the report @2026-10-16\n" +
+		"- [x] Review PRs !high @2026-10-16\n" +
+		"\n## Fri, 16 Oct 2026\n\n" +
+		"- [ ] Write release notes !med @2026-10-17\n" +
+		"- [ ] Plan the offsite !low\n" +
+		"- [ ] Fix the build !high\n" +
+		"- [ ] Fix the flaky test !high (from 2026-10-14)\n"
+	tests := []struct {
+		name     string
+		filter   TodoFilter
+		expected string
+	}{
+		{
+			name: "All",
+			expected: "#  PRIORITY  TODO                 DUE\n" +
+				"5  high      Fix the flaky test   \n" +
+				"4  high      Fix the build        \n" +
+				"2  med       Write release notes  2026-10-17\n" +
+				"3  low       Plan the offsite     \n" +
+				"1            Send the report      2026-10-16 (overdue)\n",
+		},
+		{
+			name:   "Overdue",
+			filter: TodoFilter{Overdue: true},
+			expected: "#  PRIORITY  TODO             DUE\n" +
+				"1            Send the report  2026-10-16 (overdue)\n",
+		},
+		{
+			name:   "DueToday",
+			filter: TodoFilter{Due: time.Date(2026, time.October, 17, 0, 0, 0, 0, time.Local)},
+			expected: "#  PRIORITY  TODO                 DUE\n" +
+				"2  med       Write release notes  2026-10-17\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, fixture)
+			var out strings.Builder
+			if err := ListTodos(&out, path, tc.filter, now); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.expected {
+				t.Errorf("ListTodos() printed:\n%q\nexpected:\n%q", out.String(), tc.expected)
+			}
+		})
+	}
+}
+
+const subtasksFixture = `# Todo
+
+## Fri, 16 Oct 2026
+
+- [ ] Plan the offsite
+  - [x] Pick a date
+  - [ ] Book a venue
+    - [ ] Ask for quotes
+- [ ] Write release notes
+`
+
+func TestAddSubtask(t *testing.T) {
+	tests := []struct {
+		name     string
+		parent   string
+		content  string
+		width    int
+		expected string
+	}{
+		{
+			name:    "AfterExistingSubtasks",
+			parent:  "offsite",
+			content: "send the invites",
+			expected: strings.Replace(
+				subtasksFixture,
+				"    - [ ] Ask for quotes\n",
+				"    - [ ] Ask for quotes\n  - [ ] Send the invites\n",
+				1,
+			),
+		},
+		{
+			name:    "NestedSubtask",
+			parent:  "3",
+			content: "compare quotes",
+			expected: strings.Replace(
+				subtasksFixture,
+				"    - [ ] Ask for quotes\n",
+				"    - [ ] Ask for quotes\n      - [ ] Compare quotes\n",
+				1,
+			),
+		},
+		{
+			name:     "FirstSubtask",
+			parent:   "release",
+			content:  "list the fixes",
+			expected: subtasksFixture + "  - [ ] List the fixes\n",
+		},
+		{
+			name:    "WrappedWithinWidth",
+			parent:  "3",
+			content: "compare the quotes we get back",
+			width:   24,
+			expected: strings.Replace(
+				subtasksFixture,
+				"    - [ ] Ask for quotes\n",
+				"    - [ ] Ask for quotes\n      - [ ] Compare the\n        quotes we get\n        back\n",
+				1,
+			),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFixture(t, subtasksFixture)
+			if _, err := AddSubtask(path, tc.parent, tc.content, tc.width); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("AddSubtask() wrote:\n%s\nexpected:\n%s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCompleteTodoOffersParent(t *testing.T) {
+	path := writeFixture(t, subtasksFixture)
+	completed, err := CompleteTodo(path, "quotes", false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.Parent != "Book a venue" || completed.ParentPosition != 2 {
+		t.Fatalf("CompleteTodo() = %+v, expected to offer Book a venue at 2", completed)
+	}
+	if completed, err = CompleteTodo(path, "2", false, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if completed.Parent != "Plan the offsite" || completed.ParentPosition != 1 {
+		t.Fatalf("CompleteTodo() = %+v, expected to offer Plan the offsite at 1", completed)
+	}
+	if completed, err = CompleteTodo(path, "release", false, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if completed.Parent != "" {
+		t.Errorf("CompleteTodo() = %+v, expected no parent", completed)
+	}
+}
+
+func TestListTodosSubtasks(t *testing.T) {
+	path := writeFixture(t, subtasksFixture+"- [ ] Fix the build !high\n")
+	var out strings.Builder
+	if err := ListTodos(&out, path, TodoFilter{}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	expected := "#  PRIORITY  TODO                    DUE\n" +
+		"5  high      Fix the build           \n" +
+		"1            Plan the offsite (1/2)  \n" +
+		"2              Book a venue (0/1)    \n" +
+		"3                Ask for quotes      \n" +
+		"4            Write release notes     \n"
+	if out.String() != expected {
+		t.Errorf("ListTodos() printed:\n%q\nexpected:\n%q", out.String(), expected)
+	}
+}
+
+func TestRolloverTodosSubtasks(t *testing.T) {
+	path := writeFixture(t, subtasksFixture)
+	now := time.Date(2026, time.October, 17, 8, 0, 0, 0, time.Local)
+	count, err := RolloverTodos(path, false, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Todo\n\n## Sat, 17 Oct 2026\n\n" +
+		"- [ ] Plan the offsite (from 2026-10-16)\n" +
+		"  - [x] Pick a date\n" +
+		"  - [ ] Book a venue\n" +
+		"    - [ ] Ask for quotes\n" +
+		"- [ ] Write release notes (from 2026-10-16)\n"
+	if count != 2 || string(got) != expected {
+		t.Errorf("RolloverTodos() = %d, wrote:\n%s\nexpected:\n%s", count, got, expected)
+	}
+}
+
+func TestAnnotateTodoWithDetails(t *testing.T) {
+	item, err := todo{verbatim: true}.toMarkdown(context.Background(), "fix the flaky test\n--- FAIL: TestFetch\nexit 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	details := "  ```\n  --- FAIL: TestFetch\n  exit 1\n  ```\n"
+	if expected := "- [ ] Fix the flaky test\n" + details; item != expected {
+		t.Fatalf("toMarkdown() = %q, expected %q", item, expected)
+	}
+	fixture := "# Todo\n\n## Fri, 16 Oct 2026\n\n" + item + "- [ ] Next\n"
+
+	path := writeFixture(t, fixture)
+	if _, err = CompleteTodo(path, "flaky", true, time.Date(2026, time.October, 16, 9, 30, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Todo\n\n## Fri, 16 Oct 2026\n\n" +
+		"- [x] Fix the flaky test (done 2026-10-16 09:30)\n" + details + "- [ ] Next\n"
+	if string(got) != expected {
+		t.Errorf("CompleteTodo() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+	if _, err = ReopenTodo(path, "flaky"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = os.ReadFile(path); string(got) != fixture {
+		t.Errorf("ReopenTodo() wrote:\n%s\nexpected:\n%s", got, fixture)
+	}
+
+	path = writeFixture(t, strings.TrimSuffix(fixture, "- [ ] Next\n"))
+	if _, err = RolloverTodos(path, false, time.Date(2026, time.October, 17, 8, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	expected = "# Todo\n\n## Sat, 17 Oct 2026\n\n" +
+		"- [ ] Fix the flaky test (from 2026-10-16)\n" + details
+	if string(got) != expected {
+		t.Errorf("RolloverTodos() wrote:\n%s\nexpected:\n%s", got, expected)
+	}
+}
@@ -0,0 +1,199 @@
+package note
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// maxPageSize caps how much of a page is read when looking for its metadata,
+// which lives in the head.
+const maxPageSize = 1 << 20
+
+// pageMeta is what a web page says about itself in its head. Fields the page
+// does not provide are empty.
+type pageMeta struct {
+	title       string
+	description string
+	// canonical is the URL the page gives as its own. Bookmarks keep the URL
+	// they were made with.
+	canonical string
+	siteName  string
+}
+
+// fetchPageMeta downloads the page at pageURL and extracts its metadata,
+// giving up when ctx is done.
+func fetchPageMeta(ctx context.Context, pageURL string) (pageMeta, error) {
+	doc, base, err := fetchPage(ctx, pageURL, maxPageSize)
+	if err != nil {
+		return pageMeta{}, err
+	}
+	return readPageMeta(doc, base), nil
+}
+
+// fetchPage downloads and parses up to limit bytes of the HTML page at
+// pageURL, giving up when ctx is done. It also returns the URL the page was
+// served from, after redirects.
+func fetchPage(ctx context.Context, pageURL string, limit int64) (*html.Node, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("server responded with %s", resp.Status)
+	}
+	doc, err := parseHTML(io.LimitReader(resp.Body, limit), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, resp.Request.URL, nil
+}
+
+// parseHTML parses the HTML page in r, decoding it from the charset named in
+// contentType or in the page itself.
+func parseHTML(r io.Reader, contentType string) (*html.Node, error) {
+	r, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return html.Parse(r)
+}
+
+// readPageMeta reads the metadata in the head of doc. OpenGraph and Twitter
+// card tags take precedence over <title> and the plain description, and the
+// canonical URL is resolved against base.
+func readPageMeta(doc *html.Node, base *url.URL) pageMeta {
+	var title string
+	properties := map[string]string{}
+	var meta pageMeta
+	var traverse func(*html.Node)
+	traverse = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				if title == "" {
+					title = textContent(n)
+				}
+			case "meta":
+				key := attr(n, "property")
+				if key == "" {
+					key = attr(n, "name")
+				}
+				key = strings.ToLower(key)
+				if _, ok := properties[key]; !ok && key != "" {
+					properties[key] = attr(n, "content")
+				}
+			case "link":
+				if meta.canonical == "" && strings.EqualFold(attr(n, "rel"), "canonical") {
+					meta.canonical = attr(n, "href")
+				}
+			case "body":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			traverse(c)
+		}
+	}
+	traverse(doc)
+	meta.title = firstNonBlank(properties["og:title"], properties["twitter:title"], title)
+	meta.description = firstNonBlank(
+		properties["og:description"],
+		properties["twitter:description"],
+		properties["description"],
+	)
+	meta.siteName = firstNonBlank(properties["og:site_name"])
+	meta.canonical = resolveURL(base, firstNonBlank(meta.canonical, properties["og:url"]))
+	return meta
+}
+
+// textContent returns the text inside n with runs of whitespace collapsed.
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func firstNonBlank(values ...string) string {
+	for _, v := range values {
+		if v = strings.Join(strings.Fields(v), " "); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// isWebURL reports whether s is an absolute http or https URL.
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// normalizeURL rewrites web URLs that point to the same page the same way: the
+// scheme and host are lower-cased, tracking parameters and trailing slashes
+// are dropped and the query parameters are sorted. Anything else is returned
+// as is.
+func normalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !isWebURL(raw) {
+		return raw
+	}
+	u, _ := url.Parse(raw)
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if strings.HasPrefix(strings.ToLower(key), "utm_") || strings.EqualFold(key, "fbclid") {
+				query.Del(key)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	return u.String()
+}
+
+// resolveURL makes ref absolute against base, returning "" unless the result
+// is a web URL.
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
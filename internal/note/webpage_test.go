@@ -0,0 +1,173 @@
+package note
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchPageMeta(t *testing.T) {
+	pages := map[string]struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		"/plain": {
+			body: "<html><head><title>\n  Plain   page </title></head><body>Hi</body></html>",
+		},
+		"/empty-title": {
+			body: "<html><head><title></title></head></html>",
+		},
+		"/entities": {
+			body: "<title>Tom &amp; Jerry&#39;s &quot;Show&quot;</title>",
+		},
+		"/opengraph": {
+			body: `<html><head>
+<title>Fallback</title>
+<meta name="description" content="Plain description">
+<meta property="og:title" content="OpenGraph title">
+<meta property="og:description" content="OpenGraph description">
+<meta property="og:site_name" content="Example">
+<link rel="canonical" href="/canonical?ref=feed">
+</head></html>`,
+		},
+		"/twitter": {
+			body: `<title>Fallback</title>
+<meta name="twitter:title" content="Twitter title">
+<meta name="DESCRIPTION" content="Plain description">
+<meta property="og:url" content="https://example.com/og">`,
+		},
+		"/latin1-header": {
+			contentType: "text/html; charset=iso-8859-1",
+			body:        "<title>Caf\xe9 cr\xe8me</title>",
+		},
+		"/latin1-meta": {
+			body: `<meta charset="windows-1252"><title>Caf` + "\xe9 \x93quoted\x94</title>",
+		},
+		"/missing": {
+			status: http.StatusNotFound,
+			body:   "<title>Not Found</title>",
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		contentType := page.contentType
+		if contentType == "" {
+			contentType = "text/html"
+		}
+		w.Header().Set("Content-Type", contentType)
+		if page.status != 0 {
+			w.WriteHeader(page.status)
+		}
+		w.Write([]byte(page.body))
+	}))
+	defer server.Close()
+	tests := []struct {
+		path     string
+		expected pageMeta
+		wantErr  bool
+	}{
+		{path: "/plain", expected: pageMeta{title: "Plain page"}},
+		{path: "/empty-title", expected: pageMeta{}},
+		{path: "/entities", expected: pageMeta{title: `Tom & Jerry's "Show"`}},
+		{
+			path: "/opengraph",
+			expected: pageMeta{
+				title:       "OpenGraph title",
+				description: "OpenGraph description",
+				canonical:   server.URL + "/canonical?ref=feed",
+				siteName:    "Example",
+			},
+		},
+		{
+			path: "/twitter",
+			expected: pageMeta{
+				title:       "Twitter title",
+				description: "Plain description",
+				canonical:   "https://example.com/og",
+			},
+		},
+		{path: "/latin1-header", expected: pageMeta{title: "Café crème"}},
+		{path: "/latin1-meta", expected: pageMeta{title: "Café “quoted”"}},
+		{path: "/missing", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(strings.TrimPrefix(tc.path, "/"), func(t *testing.T) {
+			got, err := fetchPageMeta(context.Background(), server.URL+tc.path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("fetchPageMeta() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.expected {
+				t.Errorf("fetchPageMeta() = %+v, expected %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBookmarkUsesPageMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`<title>Post</title>
+<meta name="description" content="What the post is about.">
+<meta property="og:site_name" content="Blog">`))
+	}))
+	defer server.Close()
+	tests := []struct {
+		name     string
+		bookmark bookmark
+		expected string
+	}{
+		{
+			name:     "PageDescription",
+			bookmark: bookmark{},
+			expected: "\n[Post | Blog](" + server.URL + ")\\\ntags:\nWhat the post is about.\n",
+		},
+		{
+			name:     "OwnDescription",
+			bookmark: bookmark{description: "Read later"},
+			expected: "\n[Post | Blog](" + server.URL + ")\\\ntags:\nRead later\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.bookmark.toMarkdown(context.Background(), server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Errorf("toMarkdown() = %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBookmarkFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	b := bookmark{timeout: 50 * time.Millisecond}
+	start := time.Now()
+	got, err := b.toMarkdown(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("toMarkdown() took %s, expected it to give up after %s", elapsed, b.timeout)
+	}
+	expected := "\n[" + server.URL + "](" + server.URL + ")\\\ntags:\n" + unfetchedMarker + "\n"
+	if got != expected {
+		t.Errorf("toMarkdown() = %q, expected %q", got, expected)
+	}
+}